@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+	"github.com/jquag/tui-do/style"
+)
+
+// backupBrowser is the state of the backups modal.
+type backupBrowser struct {
+  backups []repo.Backup
+  cursor int
+  diff []repo.DiffLine
+  showingDiff bool
+  offset int
+}
+
+func (m Model) openBackups() Model {
+  backups, err := m.Svc.Backups()
+  if err != nil {
+    m.err = err
+    return m
+  }
+  m.backups = &backupBrowser{backups: backups}
+  m.backupsModal.Title = "Backups"
+  return m
+}
+
+func (m Model) updateBackups(msg tea.KeyMsg) (Model, tea.Cmd) {
+  b := *m.backups
+  m.backups = &b
+
+  switch msg.String() {
+    case "ctrl+c", "q":
+      return m, tea.Quit
+
+    case tea.KeyEscape.String():
+      if b.showingDiff {
+        b.showingDiff = false
+      } else {
+        m.backups = nil
+      }
+
+    case "up", "k":
+      if b.showingDiff {
+        if b.offset > 0 {
+          b.offset--
+        }
+      } else if b.cursor > 0 {
+        b.cursor--
+      }
+
+    case "down", "j":
+      if b.showingDiff {
+        if b.offset < len(b.diff) - 1 {
+          b.offset++
+        }
+      } else if b.cursor < len(b.backups) - 1 {
+        b.cursor++
+      }
+
+    case "d", tea.KeyEnter.String():
+      if len(b.backups) > 0 && !b.showingDiff {
+        diff, err := m.Svc.BackupDiff(b.backups[b.cursor])
+        if err != nil {
+          m.err = err
+          m.backups = nil
+          return m, nil
+        }
+        b.diff = diff
+        b.showingDiff = true
+        b.offset = 0
+      }
+
+    case "r":
+      if len(b.backups) > 0 {
+        m.backups = nil
+        return m, restoreBackupCommand(m.Svc, b.backups[b.cursor])
+      }
+  }
+
+  return m, nil
+}
+
+func (m Model) backupsBodyView() string {
+  b := m.backups
+  if len(b.backups) == 0 {
+    return "\n" + style.Muted.Render("No backups yet") + "\n\n" + style.Muted.Render("ESC-close")
+  }
+
+  maxLines := m.height - 10
+  if maxLines < 3 {
+    maxLines = 3
+  }
+
+  var lines []string
+  if b.showingDiff {
+    lines = append(lines, style.Muted.Render("Changes since " + b.backups[b.cursor].Name()))
+    for i := b.offset; i < len(b.diff) && i - b.offset < maxLines; i++ {
+      line := b.diff[i]
+      switch line.Op {
+      case repo.Added:
+        lines = append(lines, style.DiffAdded.Render(line.String()))
+      case repo.Removed:
+        lines = append(lines, style.DiffRemoved.Render(line.String()))
+      default:
+        lines = append(lines, style.Muted.Render(line.String()))
+      }
+    }
+    return "\n" + strings.Join(lines, "\n") + "\n\n" + style.Muted.Render("j/k-scroll, r-restore, ESC-back")
+  }
+
+  start := 0
+  if b.cursor >= maxLines {
+    start = b.cursor - maxLines + 1
+  }
+  for i := start; i < len(b.backups) && i - start < maxLines; i++ {
+    line := fmt.Sprintf(" %s ", b.backups[i].Time.Format("2006-01-02 15:04:05"))
+    if i == b.cursor {
+      line = style.Highlight.Render(line)
+    }
+    lines = append(lines, line)
+  }
+  return "\n" + strings.Join(lines, "\n") + "\n\n" + style.Muted.Render("d-diff, r-restore, ESC-close")
+}
+
+func restoreBackupCommand(service *service.Service, b repo.Backup) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.RestoreBackup(b); err != nil {
+      return errMsg{event: "backup-restored", err: err}
+    }
+    return "backup-restored"
+  }
+}
@@ -24,6 +24,8 @@ type Model struct {
   ActiveIndex int
   KeyMap KeyMap
   Width int
+  // Info is shown right-aligned in the space after the tabs, if it fits
+  Info string
 }
 
 func New(tabs... string) Model {
@@ -71,7 +73,12 @@ func (m Model) View() string {
   }
   w := lipgloss.Width(lipgloss.JoinHorizontal(lipgloss.Bottom, tabStrings...))
   if w < m.Width {
-    tabStrings = append(tabStrings, style.TabFiller.Render(strings.Repeat(" ", m.Width - w - 4)))
+    fill := m.Width - w - 4
+    info := m.Info
+    if lipgloss.Width(info) > fill - 1 {
+      info = ""
+    }
+    tabStrings = append(tabStrings, style.TabFiller.Render(strings.Repeat(" ", fill - lipgloss.Width(info)) + info))
   }
   return lipgloss.JoinHorizontal(lipgloss.Bottom, tabStrings...)
 }
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+)
+
+const defaultFilename = ".tuido.json"
+
+var (
+  format = flag.String("format", "", "storage format of the todo file: " + strings.Join(repo.Formats, ", ") + " (default from the file extension)")
+  backupKeep = flag.Int("backup-keep", repo.DefaultBackupKeep, "number of automatic backups to keep, 0 disables them")
+  backupInterval = flag.Duration("backup-interval", repo.DefaultBackupInterval, "minimum time between automatic backups")
+  configFile = flag.String("config", defaultConfigFile(), "config file with the saved views")
+)
+
+// defaultConfigFile is config.json in the user's config directory.
+func defaultConfigFile() string {
+  dir, err := os.UserConfigDir()
+  if err != nil {
+    return ""
+  }
+  return filepath.Join(dir, "tui-do", "config.json")
+}
+
+func todoFilename(args []string) string {
+  if len(args) > 0 {
+    return args[0]
+  }
+  return defaultFilename
+}
+
+// openRepo opens the todo file with the backup policy from the flags.
+func openRepo(filename string) (*repo.Repo, error) {
+  r, err := repo.Open(filename, *format)
+  if err != nil {
+    return nil, err
+  }
+  if *backupKeep > 0 {
+    r.EnableBackups(repo.BackupPolicy{
+      Dir: repo.BackupDir(filename),
+      Keep: *backupKeep,
+      Interval: *backupInterval,
+    })
+  }
+  return r, nil
+}
+
+// runBackups implements the backups subcommand:
+//
+//   tui-do backups [list] [file]
+//   tui-do backups diff <backup> [file]
+//   tui-do backups restore <backup> [file]
+//
+// where <backup> is either the number shown by list or the backup's name.
+func runBackups(args []string) error {
+  flags := flag.NewFlagSet("backups", flag.ExitOnError)
+  flags.StringVar(format, "format", "", "storage format of the todo file")
+  flags.Usage = func() {
+    fmt.Fprintln(flags.Output(), "usage: tui-do backups [list] [file]")
+    fmt.Fprintln(flags.Output(), "       tui-do backups diff <backup> [file]")
+    fmt.Fprintln(flags.Output(), "       tui-do backups restore <backup> [file]")
+  }
+  flags.Parse(args)
+  args = flags.Args()
+
+  command := "list"
+  if len(args) > 0 && (args[0] == "list" || args[0] == "diff" || args[0] == "restore") {
+    command, args = args[0], args[1:]
+  }
+
+  if command == "list" {
+    filename := todoFilename(args)
+    backups, err := repo.ListBackups(repo.BackupDir(filename))
+    if err != nil {
+      return err
+    }
+    if len(backups) == 0 {
+      fmt.Println("no backups of", filename)
+    }
+    for i, b := range backups {
+      fmt.Printf("%3d  %s  %s\n", i+1, b.Time.Format("2006-01-02 15:04:05"), b.Path)
+    }
+    return nil
+  }
+
+  if len(args) == 0 {
+    flags.Usage()
+    return errors.New("missing backup")
+  }
+  filename := todoFilename(args[1:])
+  b, err := findBackup(filename, args[0])
+  if err != nil {
+    return err
+  }
+
+  r, err := openRepo(filename)
+  if err != nil {
+    return err
+  }
+  defer r.Close()
+
+  if command == "restore" {
+    if err := r.Restore(b); err != nil {
+      return err
+    }
+    fmt.Println("restored", filename, "from", b.Path)
+    return nil
+  }
+
+  todos, err := repo.LoadBackup(b.Path)
+  if err != nil {
+    return err
+  }
+  for _, line := range repo.Diff(todos, r.Todos) {
+    if line.Op != repo.Same {
+      fmt.Println(line)
+    }
+  }
+  return nil
+}
+
+func findBackup(filename string, ref string) (repo.Backup, error) {
+  backups, err := repo.ListBackups(repo.BackupDir(filename))
+  if err != nil {
+    return repo.Backup{}, err
+  }
+
+  if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(backups) {
+    return backups[n-1], nil
+  }
+  for _, b := range backups {
+    if b.Name() == ref || b.Path == ref {
+      return b, nil
+    }
+  }
+  return repo.Backup{}, fmt.Errorf("no backup %q of %s", ref, filename)
+}
+
+// runExport implements `tui-do export [--format f] [file]`, which writes the
+// todos to stdout in another format.
+func runExport(args []string) error {
+  flags := flag.NewFlagSet("export", flag.ExitOnError)
+  to := flags.String("format", "json", "format to export to: json, markdown, todotxt, org")
+  flags.StringVar(format, "file-format", "", "storage format of the todo file")
+  flags.Parse(args)
+
+  codec, err := repo.CodecFor(*to)
+  if err != nil {
+    return err
+  }
+  r, err := repo.OpenExisting(todoFilename(flags.Args()), *format)
+  if err != nil {
+    return err
+  }
+  defer r.Close()
+
+  content, err := codec.Encode(r.Todos)
+  if err != nil {
+    return err
+  }
+  _, err = os.Stdout.Write(content)
+  return err
+}
+
+// runImport implements `tui-do import [--format f] <source> [file]`, which
+// appends the todos read from source to the todo file.
+func runImport(args []string) error {
+  flags := flag.NewFlagSet("import", flag.ExitOnError)
+  from := flags.String("format", "", "format of the source file (default from its extension)")
+  flags.StringVar(format, "file-format", "", "storage format of the todo file")
+  flags.Parse(args)
+  args = flags.Args()
+
+  if len(args) == 0 {
+    return errors.New("usage: tui-do import [--format f] <source> [file]")
+  }
+  source := args[0]
+  if *from == "" {
+    *from = repo.FormatOf(source)
+  }
+
+  codec, err := repo.CodecFor(*from)
+  if err != nil {
+    return err
+  }
+  content, err := os.ReadFile(source)
+  if err != nil {
+    return err
+  }
+  imported, err := codec.Decode(content)
+  if err != nil {
+    return err
+  }
+
+  filename := todoFilename(args[1:])
+  r, err := openRepo(filename)
+  if err != nil {
+    return err
+  }
+  defer r.Close()
+  r.Todos = append(r.Todos, repo.CopyWithNewIds(imported)...)
+  if err := r.Persist(); err != nil {
+    return err
+  }
+  fmt.Printf("imported %d items from %s into %s\n", len(imported), source, filename)
+  return nil
+}
+
+// runList implements `tui-do list [--query q] [file]`, which prints the
+// todos as an indented checklist. With a query only the matching items are
+// listed, along with their ancestors. For a workspace directory every list
+// is printed under its name.
+func runList(args []string) error {
+  flags := flag.NewFlagSet("list", flag.ExitOnError)
+  query := flags.String("query", "", "only list the items matching this filter, like 'tag:backend and not done and due<+7d'")
+  flags.StringVar(format, "format", "", "storage format of the todo file")
+  flags.Parse(args)
+
+  var q *service.Query
+  if *query != "" {
+    var err error
+    if q, err = service.ParseQuery(*query); err != nil {
+      return err
+    }
+  }
+
+  filename := todoFilename(flags.Args())
+  files := []string{filename}
+  if info, err := os.Stat(filename); err == nil && info.IsDir() {
+    if files, err = repo.ListFiles(filename); err != nil {
+      return err
+    }
+  }
+  for i, f := range files {
+    r, err := repo.OpenExisting(f, *format)
+    if err != nil {
+      return err
+    }
+    todos := r.Todos
+    r.Close()
+    if q != nil {
+      todos = service.FilterByQuery(todos, q, time.Now())
+    }
+    if f != filename {
+      if i > 0 {
+        fmt.Println()
+      }
+      fmt.Println(repo.ListName(f) + ":")
+    }
+    printList(todos, "")
+  }
+  return nil
+}
+
+// printList prints todos as the checklist of the list subcommand.
+func printList(todos []repo.Todo, indent string) {
+  for _, t := range todos {
+    line := indent + "[ ] "
+    if t.Done {
+      line = indent + "[x] "
+    }
+    if t.Priority != "" {
+      line += "(" + t.Priority + ") "
+    }
+    line += repo.JoinTags(t.Name, t.Tags)
+    if t.Due != "" {
+      line += "  due " + t.Due
+    }
+    fmt.Println(line)
+    printList(t.Children, indent + "  ")
+  }
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+)
+
+// editedMsg is sent when the editor opened on an item exits.
+type editedMsg struct {
+  item repo.Todo
+  path string
+  // outline is set when the whole subtree was edited as a checklist
+  outline bool
+  err error
+}
+
+// editInEditor suspends the program and opens the item, or with outline
+// its whole subtree, in the user's editor.
+func editInEditor(service *service.Service, item repo.Todo, outline bool) tea.Cmd {
+  text := service.EditText(item)
+  if outline {
+    var err error
+    if text, err = service.OutlineText(item); err != nil {
+      return func() tea.Msg { return errMsg{event: "todo-edited", err: err} }
+    }
+  }
+
+  f, err := os.CreateTemp("", "tui-do-*.md")
+  if err == nil {
+    _, err = f.WriteString(text)
+    if closeErr := f.Close(); err == nil {
+      err = closeErr
+    }
+  }
+  if err != nil {
+    return func() tea.Msg { return errMsg{event: "todo-edited", err: err} }
+  }
+
+  return tea.ExecProcess(editorCommand(f.Name()), func(err error) tea.Msg {
+    return editedMsg{item: item, path: f.Name(), outline: outline, err: err}
+  })
+}
+
+// applyEditCommand reads back what was saved in the editor.
+func applyEditCommand(service *service.Service, msg editedMsg) tea.Cmd {
+  return func() tea.Msg {
+    defer os.Remove(msg.path)
+    if msg.err != nil {
+      return errMsg{event: "todo-edited", err: msg.err}
+    }
+
+    content, err := os.ReadFile(msg.path)
+    if err == nil {
+      if msg.outline {
+        err = service.ApplyOutlineText(msg.item, string(content))
+      } else {
+        err = service.ApplyEditText(msg.item, string(content))
+      }
+    }
+    if err != nil {
+      return errMsg{event: "todo-edited", err: err}
+    }
+    return "todo-edited"
+  }
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/style"
+)
+
+// How the Complete tab orders its items.
+const (
+  completeInFileOrder = iota
+  completeByDate
+  completeGroupedByDate
+)
+
+func (m Model) openHistory(item repo.Todo) Model {
+  m.isShowingHistory = true
+  m.historyModal.Title = "History: " + item.Name
+  m.historyModal.Body = m.historyBodyView(item)
+  return m
+}
+
+func (m Model) historyBodyView(item repo.Todo) string {
+  var lines []string
+  for _, field := range []struct{ label, value string }{
+    {"Created  ", item.CreatedAt},
+    {"Updated  ", item.UpdatedAt},
+    {"Completed", item.CompletedAt},
+  } {
+    if field.value != "" {
+      lines = append(lines, style.Muted.Render(field.label) + "  " + formatTimestamp(field.value))
+    }
+  }
+  if len(lines) > 0 {
+    lines = append(lines, "")
+  }
+
+  maxLines := clamp(m.height - 14, 3, 100)
+  if len(item.History) == 0 {
+    lines = append(lines, style.Muted.Render("No history yet"))
+  }
+  for i := len(item.History) - 1; i >= 0 && len(item.History) - i <= maxLines; i-- {
+    e := item.History[i]
+    line := style.Muted.Render(formatTimestamp(e.At)) + "  " + style.ActionStyle.Render(e.Action)
+    if e.From != "" || e.To != "" {
+      line += fmt.Sprintf(" %q → %q", e.From, e.To)
+    }
+    lines = append(lines, line)
+  }
+
+  return "\n" + strings.Join(lines, "\n") + "\n\n" + style.Muted.Render("ESC-close")
+}
+
+func formatTimestamp(timestamp string) string {
+  t, ok := repo.ParseTimestamp(timestamp)
+  if !ok {
+    return timestamp
+  }
+  if len(timestamp) == len(repo.DateFormat) {
+    return t.Format("Mon Jan 02 2006")
+  }
+  return t.Local().Format("Mon Jan 02 2006 15:04")
+}
+
+// completeOrderNotice describes the order of the Complete tab.
+func completeOrderNotice(order int) string {
+  switch order {
+  case completeByDate:
+    return "Completed items sorted by completion date"
+  case completeGroupedByDate:
+    return "Completed items grouped by completion date"
+  }
+  return "Completed items in list order"
+}
+
+// isGrouped reports whether the list shows group headers.
+func (m Model) isGrouped() bool {
+  return m.groupBy() != ""
+}
+
+// groupBy is how the active tab groups its items, see service.GroupOf, or
+// "" if it doesn't.
+func (m Model) groupBy() string {
+  if saved := m.views[m.Tabs.ActiveIndex].saved; saved != nil {
+    return saved.Group
+  }
+  if m.Tabs.ActiveIndex == 1 && m.completeOrder == completeGroupedByDate {
+    return "completed"
+  }
+  return ""
+}
+
+// groupHeader is the header to show above a top-level item, if it starts a
+// new group.
+func (m Model) groupHeader(todos []repo.Todo, i int, now time.Time) string {
+  group := m.Svc.GroupOf(todos[i], m.groupBy(), now)
+  if i > 0 && m.Svc.GroupOf(todos[i-1], m.groupBy(), now) == group {
+    return ""
+  }
+  return style.GroupHeader.Render(group)
+}
+
+// lineOf is the line of the list a row is shown on, which is further down
+// than the row when there are group headers.
+func (m Model) lineOf(todos []repo.Todo, row int) int {
+  if !m.isGrouped() {
+    return row
+  }
+  now := time.Now()
+  line, start := row, 0
+  for i, t := range todos {
+    if start > row {
+      break
+    }
+    if m.groupHeader(todos, i, now) != "" {
+      line++
+    }
+    start += 1
+    if t.Expanded {
+      start += m.countRows(t.Children)
+    }
+  }
+  return line
+}
+
+// scrollToLine scrolls the list just enough for line to be visible.
+func (m Model) scrollToLine(line int) Model {
+  if line < m.ListViewport.YOffset {
+    m.ListViewport.YOffset = line
+  } else if line >= m.ListViewport.YOffset + m.ListViewport.Height {
+    m.ListViewport.YOffset = line - m.ListViewport.Height + 1
+  }
+  return m
+}
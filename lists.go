@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+	"github.com/jquag/tui-do/style"
+)
+
+// listSwitcher is the state of the lists modal. With moving set, picking a
+// list moves that item there rather than switching to it.
+type listSwitcher struct {
+  names []string
+  cursor int
+  moving *repo.Todo
+}
+
+// openWorkspace opens every list in the workspace directory dir. The lists
+// that couldn't be opened are left out and returned as failed. A directory
+// without lists gets an empty todo.json.
+func openWorkspace(dir string) (*service.Service, []string, error) {
+  files, err := repo.ListFiles(dir)
+  if err != nil {
+    return nil, nil, err
+  }
+  if len(files) == 0 {
+    files = []string{filepath.Join(dir, "todo.json")}
+  }
+
+  var lists []service.List
+  var failed []string
+  var errs []error
+  for _, f := range files {
+    r, err := openRepo(f)
+    if err != nil {
+      failed = append(failed, f)
+      errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(f), err))
+      continue
+    }
+    lists = append(lists, service.List{Name: repo.ListName(f), Repo: r})
+  }
+  if len(lists) == 0 {
+    return nil, failed, errors.Unwrap(errs[0])
+  }
+  return service.NewWorkspace(lists), failed, errors.Join(errs...)
+}
+
+func (m Model) openLists(moving *repo.Todo) Model {
+  names := m.Svc.Lists()
+  if len(names) == 0 {
+    m.notice = "There is only one list, open a directory to work with several"
+    return m
+  }
+
+  l := &listSwitcher{names: names, moving: moving}
+  for i, name := range names {
+    if name == m.Svc.ActiveList() {
+      l.cursor = i
+    }
+  }
+  m.lists = l
+  m.listsModal.Title = "Lists"
+  if moving != nil {
+    m.listsModal.Title = "Move '" + moving.Name + "' to"
+  }
+  return m
+}
+
+func (m Model) updateLists(msg tea.KeyMsg) (Model, tea.Cmd) {
+  l := *m.lists
+  m.lists = &l
+
+  switch msg.String() {
+    case "ctrl+c", "q":
+      return m, tea.Quit
+
+    case tea.KeyEscape.String():
+      m.lists = nil
+
+    case "up", "k":
+      if l.cursor > 0 {
+        l.cursor--
+      }
+
+    case "down", "j":
+      if l.cursor < len(l.names) - 1 {
+        l.cursor++
+      }
+
+    case tea.KeyEnter.String():
+      m.lists = nil
+      name := l.names[l.cursor]
+      if l.moving != nil {
+        return m, moveToListCommand(m.Svc, *l.moving, name)
+      }
+      if name == m.Svc.ActiveList() {
+        return m, nil
+      }
+      if err := m.Svc.SwitchList(name); err != nil {
+        m.err = err
+        return m, nil
+      }
+      for i := range m.views {
+        m.views[i].cursorRow = 0
+        m.views[i].offset = 0
+      }
+      m.ListViewport.SetYOffset(0)
+      m.notice = "Switched to " + name
+  }
+
+  return m, nil
+}
+
+func (m Model) listsBodyView() string {
+  var lines []string
+  for i, name := range m.lists.names {
+    line := " " + name + " "
+    if name == m.Svc.ActiveList() {
+      line = " " + name + " " + style.Muted.Render("(current) ")
+    }
+    if i == m.lists.cursor {
+      line = style.Highlight.Render(line)
+    }
+    lines = append(lines, line)
+  }
+  return "\n" + strings.Join(lines, "\n") + "\n\n" + style.Muted.Render("enter-pick, ESC-close")
+}
+
+func moveToListCommand(service *service.Service, item repo.Todo, name string) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.MoveToList(item, name); err != nil {
+      return errMsg{event: "todo-moved-to-list", err: err}
+    }
+    return "todo-moved-to-list"
+  }
+}
+
+// listHeader names the active list of a workspace, for the header.
+func (m Model) listHeader() string {
+  if m.Svc == nil || len(m.Svc.Lists()) == 0 {
+    return ""
+  }
+  return style.Muted.Render("list: ") + style.ActionStyle.Render(m.Svc.ActiveList())
+}
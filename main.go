@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -25,17 +27,29 @@ import (
 // tea.EnterAltScreen().
 const useHighPerformanceRenderer = false
 
+const inputPrompt = ">  "
+
 type Model struct {
   Svc *service.Service
   isAdding bool
   isAddingChild bool
   isDeleting bool
   isEditing bool
-  todoCursorRow int
-  completedCursorRow int
+  isSettingDue bool
+  isSettingRepeat bool
+  isFilteringTags bool
+  isSearching bool
+  isQuerying bool
+  // query hides the items that don't match it, see service.Query
+  query *service.Query
+  // search highlights the items matching it, n/N jump between them
+  search string
+  // tagFilter hides the items that don't carry all of these tags
+  tagFilter []string
   Tabs tabs.Model
+  // views has the state of each of the Tabs
+  views []view
   ListViewport viewport.Model
-  inactiveTabViewportOffset int
   ready bool
   textInput textinput.Model
   width int
@@ -43,69 +57,173 @@ type Model struct {
   confirmationModal modal.Model
   helpModal modal.Model
   isShowingHelp bool
+  historyModal modal.Model
+  isShowingHistory bool
+  completeOrder int
+  backupsModal modal.Model
+  backups *backupBrowser
+  notesModal modal.Model
+  notes *notesEditor
+  listsModal modal.Model
+  lists *listSwitcher
+  showDetail bool
+  err error
+  notice string
+  recovery *recovery
+  // followId is the item the cursor should stay on once a reorder lands
+  followId string
+  // register is the subtree last yanked or cut, for pasting. registerCut is
+  // set until a cut subtree is pasted, later pastes are copies.
+  register *repo.Todo
+  registerCut bool
 } 
 
-func (m Model) cursorRow() int {
-  if m.Tabs.ActiveIndex == 0 {
-    return m.todoCursorRow
+// errMsg reports that a service call failed. The change itself has already
+// been applied in memory, so event is handled like a regular success message.
+type errMsg struct {
+  event string
+  err error
+}
+
+// isTyping reports whether the text input has the focus.
+func (m Model) isTyping() bool {
+  return m.isAdding || m.isAddingChild || m.isEditing || m.isSettingDue || m.isSettingRepeat || m.isFilteringTags || m.isSearching || m.isQuerying
+}
+
+// todos are the items shown on the active tab.
+func (m Model) todos() []repo.Todo {
+  var todos []repo.Todo
+  if saved := m.views[m.Tabs.ActiveIndex].saved; saved != nil {
+    todos = m.Svc.ViewTodos(*saved, time.Now())
   } else {
-    return m.completedCursorRow
+    todos = m.Svc.Todos(m.Tabs.ActiveIndex == 1)
+  }
+  if m.Tabs.ActiveIndex == 1 && m.completeOrder != completeInFileOrder {
+    todos = m.Svc.SortByCompletion(todos)
+  }
+  if len(m.tagFilter) > 0 {
+    todos = service.FilterByTags(todos, m.tagFilter)
   }
+  if m.query != nil {
+    todos = service.FilterByQuery(todos, m.query, time.Now())
+  }
+  return todos
+}
+
+func (m Model) cursorRow() int {
+  return m.views[m.Tabs.ActiveIndex].cursorRow
 }
 
 func (m *Model) incCursorRow() {
-  if m.Tabs.ActiveIndex == 0 {
-    m.todoCursorRow++
-  } else {
-    m.completedCursorRow++
-  }
+  m.views[m.Tabs.ActiveIndex].cursorRow++
 }
 
 func (m *Model) decCursorRow() {
-  if m.Tabs.ActiveIndex == 0 {
-    m.todoCursorRow--
-  } else {
-    m.completedCursorRow--
-  }
+  m.views[m.Tabs.ActiveIndex].cursorRow--
 }
 
 func (m *Model) setCursorRow(row int) {
-  if m.Tabs.ActiveIndex == 0 {
-    m.todoCursorRow = row
-  } else {
-    m.completedCursorRow = row
-  }
+  m.views[m.Tabs.ActiveIndex].cursorRow = row
 }
 
-func initialModel() Model {
-  filename := ".tuido.json"
-  if (len(os.Args) > 1) {
-    filename = os.Args[1]
-  }
-  r := repo.NewRepo(filename)
-  s := service.NewService(r)
-
+func initialModel(filename string) Model {
   ti := textinput.New()
 	ti.Width = 20
   ti.Cursor.SetMode(cursor.CursorBlink)
-  ti.Prompt = ">  "
+  ti.Prompt = inputPrompt
   ti.TextStyle = style.ActionStyle
   ti.PromptStyle = ti.PromptStyle.Inherit(style.ActionStyle)
 
-  return Model{
-    Svc: s,
-    Tabs: tabs.New("TODO", "Complete"),
+  m := Model{
     textInput: ti,
   }
+  m = m.loadViews()
+
+  if info, err := os.Stat(filename); err == nil && info.IsDir() {
+    svc, failed, err := openWorkspace(filename)
+    switch {
+    case svc != nil:
+      m.Svc = svc
+      if err != nil {
+        m.err = err
+      }
+    case len(failed) > 0:
+      m.recovery = newRecovery(failed[0], err)
+      m.recovery.workspace = filename
+    default:
+      m.recovery = newRecovery(filename, err)
+    }
+    return m
+  }
+
+  r, err := openRepo(filename)
+  if err != nil {
+    m.recovery = newRecovery(filename, err)
+  } else {
+    m.Svc = service.NewService(r)
+  }
+  return m
+}
+
+// externalChangeMsg is sent when the todo file was changed by someone else.
+type externalChangeMsg struct{}
+
+// cutMsg hands over a subtree taken out of the tree. It's still set when
+// err is, as the cut has been applied in memory.
+type cutMsg struct {
+  item repo.Todo
+  err error
+}
+
+// pastedMsg reports the id of a pasted subtree.
+type pastedMsg struct {
+  itemId string
+  err error
+}
+
+// undoMsg reports an undo, or a redo, and what it reverted. label is empty
+// if there was nothing to revert.
+type undoMsg struct {
+  label string
+  redo bool
+}
+
+func (m Model) stopTyping() Model {
+  m.isAdding = false
+  m.isAddingChild = false
+  m.isEditing = false
+  m.isSettingDue = false
+  m.isSettingRepeat = false
+  m.isFilteringTags = false
+  m.isSearching = false
+  m.isQuerying = false
+  m.textInput.Prompt = inputPrompt
+  m.textInput.Placeholder = ""
+  return m
 }
 
 func (m Model) Init() tea.Cmd {
-  return nil
+  if m.Svc == nil {
+    return nil
+  }
+  return watchCommand(m.Svc)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+  if m.recovery != nil {
+    return m.updateRecovery(msg)
+  }
+
+  if e, ok := msg.(errMsg); ok {
+    m.err = e.err
+    msg = e.event
+  } else if _, ok := msg.(tea.KeyMsg); ok {
+    m.err = nil
+    m.notice = ""
+  }
+
   initialModel := m
-  todos := m.Svc.Todos(m.Tabs.ActiveIndex == 1)
+  todos := m.todos()
   totalRows := m.countRows(todos)
   skipViewportUpdate := false
   cursorRow := m.cursorRow()
@@ -119,7 +237,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
   switch msg := msg.(type) {
   case tea.KeyMsg:
-    if !m.isAdding && !m.isAddingChild && !m.isDeleting && !m.isEditing && !m.isShowingHelp {
+    if !m.isTyping() && !m.isDeleting && !m.isShowingHelp && !m.isShowingHistory && m.backups == nil && m.notes == nil && m.lists == nil {
       switch msg.String() {
 
         case "ctrl+c", "q":
@@ -158,7 +276,47 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         case "c":
           m.isEditing = true
           m.textInput.Focus()
-          m.textInput.SetValue(currentItem.Name)
+          m.textInput.SetValue(repo.JoinTags(currentItem.Name, currentItem.Tags))
+          m.textInput.CursorEnd()
+          cmd := m.textInput.Cursor.BlinkCmd()
+          cmds = append(cmds, cmd)
+
+        case "t":
+          if currentItem != nil {
+            m.isSettingDue = true
+            m.textInput.Prompt = "due: "
+            m.textInput.Focus()
+            m.textInput.SetValue(currentItem.Due)
+            m.textInput.CursorEnd()
+            cmd := m.textInput.Cursor.BlinkCmd()
+            cmds = append(cmds, cmd)
+          }
+
+        case "R":
+          if currentItem != nil {
+            m.isSettingRepeat = true
+            m.textInput.Prompt = "repeat: "
+            m.textInput.Placeholder = "daily, weekly mon,thu, monthly 15, every 3d, cron 1-7 * mon"
+            m.textInput.Focus()
+            m.textInput.SetValue(currentItem.Repeat)
+            m.textInput.CursorEnd()
+            cmd := m.textInput.Cursor.BlinkCmd()
+            cmds = append(cmds, cmd)
+          }
+
+        case "T":
+          var labels, known []string
+          for _, tag := range m.tagFilter {
+            labels = append(labels, repo.TagLabel(tag))
+          }
+          for _, tag := range m.Svc.AllTags() {
+            known = append(known, repo.TagLabel(tag))
+          }
+          m.isFilteringTags = true
+          m.textInput.Prompt = "tags: "
+          m.textInput.Placeholder = strings.Join(known, " ")
+          m.textInput.Focus()
+          m.textInput.SetValue(strings.Join(labels, " "))
           m.textInput.CursorEnd()
           cmd := m.textInput.Cursor.BlinkCmd()
           cmds = append(cmds, cmd)
@@ -182,6 +340,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
           m.helpModal.Title = "Key Mappings"
           m.helpModal.Body = m.helpBodyView()
 
+        case "B":
+          m = m.openBackups()
+
+        case "H":
+          if currentItem != nil {
+            m = m.openHistory(*currentItem)
+          }
+
+        case "o":
+          if m.Tabs.ActiveIndex == 1 {
+            m.completeOrder = (m.completeOrder + 1) % 3
+            m.notice = completeOrderNotice(m.completeOrder)
+            m.setCursorRow(0)
+            m.ListViewport.SetYOffset(0)
+          }
+
+        case "e":
+          if currentItem != nil {
+            m, cmd = m.openNotes(*currentItem)
+            cmds = append(cmds, cmd)
+          }
+
+        case "E":
+          if currentItem != nil {
+            cmds = append(cmds, editInEditor(m.Svc, *currentItem, false))
+          }
+
+        case "ctrl+e":
+          if currentItem != nil {
+            cmds = append(cmds, editInEditor(m.Svc, *currentItem, true))
+          }
+
+        case "v":
+          m.showDetail = !m.showDetail
+          m.ListViewport.Width = m.listWidth()
+
         case "G":
           m.setCursorRow(m.countRows(todos) - 1)
           m.ListViewport.SetYOffset(m.ListViewport.Height)
@@ -192,22 +386,162 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
         case "W":
           cmds = append(cmds, collapseAllCommand(m.Svc, m.Tabs.ActiveIndex == 1))
+
+        case "u":
+          cmds = append(cmds, undoCommand(m.Svc, false))
+
+        case "ctrl+r":
+          cmds = append(cmds, undoCommand(m.Svc, true))
+
+        case "+", "=":
+          if currentItem != nil {
+            cmds = append(cmds, raisePriorityCommand(m.Svc, *currentItem))
+          }
+
+        case "-":
+          if currentItem != nil {
+            cmds = append(cmds, lowerPriorityCommand(m.Svc, *currentItem))
+          }
+
+        case "K", "alt+up":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+            cmds = append(cmds, moveCommand(m.Svc, *currentItem, false))
+          }
+
+        case "J", "alt+down":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+            cmds = append(cmds, moveCommand(m.Svc, *currentItem, true))
+          }
+
+        case "tab", ">":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+            cmds = append(cmds, indentCommand(m.Svc, *currentItem, false))
+          }
+
+        case "shift+tab", "<":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+            cmds = append(cmds, indentCommand(m.Svc, *currentItem, true))
+          }
+
+        case "y":
+          if currentItem != nil {
+            if item, ok := m.Svc.Subtree(currentItem.Id); ok {
+              m.register = &item
+              m.registerCut = false
+              m.notice = "Yanked '" + item.Name + "'"
+            }
+          }
+
+        case "x":
+          if currentItem != nil {
+            cmds = append(cmds, cutCommand(m.Svc, currentItem.Id))
+          }
+
+        case "L":
+          m = m.openLists(nil)
+
+        case "M":
+          if currentItem != nil {
+            m = m.openLists(currentItem)
+          }
+
+        case "f":
+          m.isQuerying = true
+          m.textInput.Prompt = "filter: "
+          m.textInput.Placeholder = "tag:backend and not done and due<+7d"
+          m.textInput.SetValue("")
+          if m.query != nil {
+            m.textInput.SetValue(m.query.String())
+          }
+          m.textInput.CursorEnd()
+          m.textInput.Focus()
+
+        case "/":
+          m.isSearching = true
+          m.textInput.Prompt = "/"
+          m.textInput.SetValue(m.search)
+          m.textInput.CursorEnd()
+          m.textInput.Focus()
+
+        case "n", "N":
+          if m.search != "" {
+            m, cmd = m.jumpToHit(currentItem, msg.String() == "N")
+            cmds = append(cmds, cmd)
+          }
+
+        case "p", "P":
+          if m.register != nil {
+            cmds = append(cmds, pasteCommand(m.Svc, currentItem, *m.register, msg.String() == "P", !m.registerCut))
+            m.registerCut = false
+          }
+
+        case "s":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+            cmds = append(cmds, sortByPriorityCommand(m.Svc, currentItem))
+          }
+
+        case "S":
+          if currentItem != nil {
+            m.followId = currentItem.Id
+          }
+          cmds = append(cmds, sortByPriorityCommand(m.Svc, nil))
       }
-    } else if m.isAdding || m.isAddingChild || m.isEditing {
+    } else if m.isTyping() {
       switch msg.String() {
         case "ctrl+c":
           return m, tea.Quit
 
         case tea.KeyEscape.String():
-          m.isAdding = false
-          m.isAddingChild = false
-          m.isEditing = false
+          m = m.stopTyping()
+          if initialModel.isSearching {
+            m.search = ""
+          }
 
         case tea.KeyEnter.String():
-          m.isAdding = false
-          m.isAddingChild = false
-          m.isEditing = false
-          if initialModel.isAdding {
+          m = m.stopTyping()
+          if initialModel.isSearching {
+            m.search = strings.TrimSpace(m.textInput.Value())
+            if m.search != "" && (currentItem == nil || !service.Matches(*currentItem, m.search)) {
+              m, cmd = m.jumpToHit(currentItem, false)
+              cmds = append(cmds, cmd)
+            }
+          } else if initialModel.isFilteringTags || initialModel.isQuerying {
+            if initialModel.isQuerying {
+              m.query = nil
+              if strings.TrimSpace(m.textInput.Value()) != "" {
+                m.query, m.err = service.ParseQuery(m.textInput.Value())
+              }
+            } else {
+              m.tagFilter = nil
+              for _, tag := range strings.Fields(m.textInput.Value()) {
+                m.tagFilter = repo.AddTag(m.tagFilter, repo.NormalizeTag(tag))
+              }
+            }
+            for i := range m.views {
+              m.views[i].cursorRow = 0
+              m.views[i].offset = 0
+            }
+            m.ListViewport.SetYOffset(0)
+          } else if initialModel.isSettingRepeat {
+            rule, err := service.ParseRepeat(m.textInput.Value())
+            if err != nil {
+              m.err = err
+            } else {
+              cmds = append(cmds, setRepeatCommand(m.Svc, *currentItem, rule))
+            }
+          } else if initialModel.isSettingDue {
+            due, err := service.ParseDue(m.textInput.Value(), time.Now())
+            if err != nil {
+              m.err = err
+            } else {
+              cmds = append(cmds, setDueCommand(m.Svc, *currentItem, due))
+            }
+          } else if initialModel.isAdding {
             if len(todos) == 0 {
               cmds = append(cmds, addTodoCommand(m.Svc, nil, m.textInput.Value()))
             } else {
@@ -219,6 +553,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
               cmds = append(cmds, addTodoAsChildCommand(m.Svc, currentItem, m.textInput.Value()))
           }
       }
+    } else if m.backups != nil {
+      m, cmd = m.updateBackups(msg)
+      cmds = append(cmds, cmd)
+    } else if m.lists != nil {
+      m, cmd = m.updateLists(msg)
+      cmds = append(cmds, cmd)
+    } else if m.notes != nil {
+      m, cmd = m.updateNotes(msg)
+      cmds = append(cmds, cmd)
     } else {
       switch msg.String() {
         case "ctrl+c", "q":
@@ -235,11 +578,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     m.confirmationModal.Height = msg.Height
     m.helpModal.Width = msg.Width
     m.helpModal.Height = msg.Height
+    m.backupsModal.Width = msg.Width
+    m.backupsModal.Height = msg.Height
+    m.notesModal.Width = msg.Width
+    m.notesModal.Height = msg.Height
+    m.listsModal.Width = msg.Width
+    m.listsModal.Height = msg.Height
+    m.historyModal.Width = msg.Width
+    m.historyModal.Height = msg.Height
     headerHeight := 5 //TODO: calc this
     footerHeight := 3 //TODO: calc this
     verticalMarginHeight := headerHeight + footerHeight
     if !m.ready {
-      m.ListViewport = viewport.New(msg.Width, msg.Height-verticalMarginHeight)
+      m.ListViewport = viewport.New(m.listWidth(), msg.Height-verticalMarginHeight)
       m.ListViewport.YPosition = headerHeight
       m.ListViewport.HighPerformanceRendering = useHighPerformanceRenderer
       m.ready = true
@@ -250,7 +601,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
       // Render the viewport one line below the header.
       m.ListViewport.YPosition = headerHeight + 1
     } else {
-      m.ListViewport.Width = msg.Width
+      m.ListViewport.Width = m.listWidth()
       m.ListViewport.Height = msg.Height - verticalMarginHeight
     }
 
@@ -264,12 +615,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
       }
     }
 
-    if msg == "todo-toggled" || msg == "todo-deleted" {
+    if msg == "todo-toggled" || msg == "todo-deleted" || msg == "backup-restored" || msg == "todo-moved-to-list" {
       if (len(todos) > 0 && m.cursorRow() >= totalRows) {
         m.decCursorRow()
       }
     }
 
+    // an edited subtree or an undo can lose any number of rows
+    if (msg == "todo-edited" || msg == "todos-undone") && totalRows > 0 && m.cursorRow() >= totalRows {
+      m.setCursorRow(totalRows - 1)
+    }
+
+    if (msg == "todos-sorted" || msg == "todo-moved" || msg == "todo-revealed") && m.followId != "" {
+      m = m.followItem(todos, m.followId)
+      m.followId = ""
+    }
+
+  case undoMsg:
+    switch {
+    case msg.label == "" && msg.redo:
+      m.notice = "Nothing to redo"
+    case msg.label == "":
+      m.notice = "Nothing to undo"
+    case msg.redo:
+      m.notice = "Redid: " + msg.label
+    default:
+      m.notice = "Undid: " + msg.label
+    }
+    if totalRows > 0 && m.cursorRow() >= totalRows {
+      m.setCursorRow(totalRows - 1)
+    }
+
+  case editedMsg:
+    cmds = append(cmds, applyEditCommand(m.Svc, msg))
+
+  case cutMsg:
+    m.err = msg.err
+    if msg.item.Id != "" {
+      m.register = &msg.item
+      m.registerCut = true
+      m.notice = "Cut '" + msg.item.Name + "'"
+    }
+    if totalRows > 0 && m.cursorRow() >= totalRows {
+      m.setCursorRow(totalRows - 1)
+    }
+
+  case pastedMsg:
+    m.err = msg.err
+    m = m.followItem(todos, msg.itemId)
+
+  case externalChangeMsg:
+    merged, err := m.Svc.Reload()
+    if err != nil {
+      m.err = err
+    } else if merged {
+      m.notice = "The file changed on disk, your unsaved edits were merged in"
+    } else {
+      m.notice = "The file changed on disk and was reloaded"
+    }
+    rows := m.countRows(m.todos())
+    if m.cursorRow() >= rows && rows > 0 {
+      m.setCursorRow(rows - 1)
+    }
+    cmds = append(cmds, watchCommand(m.Svc))
+
   case modal.ModalMsg:
     if msg == modal.Confirmed {
       if m.isDeleting {
@@ -279,6 +688,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     } else if msg == modal.Cancelled {
       m.isDeleting = false
       m.isShowingHelp = false
+      m.isShowingHistory = false
     }
 
   }
@@ -286,19 +696,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
   m.ListViewport.SetContent(m.ContentView())
 
   if tabChanged {
-    m.ListViewport.SetYOffset(m.inactiveTabViewportOffset)
-    m.inactiveTabViewportOffset = initialModel.ListViewport.YOffset
+    m.views[initialModel.Tabs.ActiveIndex].offset = initialModel.ListViewport.YOffset
+    m.ListViewport.SetYOffset(m.views[m.Tabs.ActiveIndex].offset)
   }
 
-  if !skipViewportUpdate && !m.isAdding && !m.isAddingChild && !m.isEditing {
+  if _, ok := msg.(tea.KeyMsg); !ok && m.notes != nil {
+    m, cmd = m.updateNotes(msg)
+    cmds = append(cmds, cmd)
+  }
+
+  if !skipViewportUpdate && !m.isTyping() && m.backups == nil && initialModel.backups == nil && m.notes == nil && initialModel.notes == nil && m.lists == nil && initialModel.lists == nil {
     m.ListViewport, cmd = m.ListViewport.Update(msg)
     cmds = append(cmds, cmd)
   }
 
-  if initialModel.isAdding || initialModel.isAddingChild || initialModel.isEditing {
+  if initialModel.isTyping() {
     var cmd tea.Cmd
     m.textInput, cmd = m.textInput.Update(msg)
     cmds = append(cmds, cmd)
+    if m.isSearching {
+      m.search = m.textInput.Value()
+    }
   }
 
   if initialModel.isDeleting {
@@ -313,18 +731,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     cmds = append(cmds, cmd)
   }
 
+  if initialModel.isShowingHistory {
+    var cmd tea.Cmd
+    m.historyModal, cmd = m.historyModal.Update(msg)
+    cmds = append(cmds, cmd)
+  }
+
+  // the scrolling above goes by rows, which group headers push down
+  if m.isGrouped() {
+    m = m.scrollToLine(m.lineOf(m.todos(), m.cursorRow()))
+  }
+
   return m, tea.Batch(cmds...)
 }
 
 func (m Model) View() string {
+  if m.recovery != nil {
+    return m.recoveryView()
+  }
+
   if !m.ready {
 		return "\n  Initializing..."
 	}
 
   footer := "\n\n"+style.Muted.Render("Press ? for help")
+  if m.isFilteringTags || m.isSearching || m.isQuerying {
+    footer = "\n\n"+m.textInput.View()
+  } else if len(m.tagFilter) > 0 {
+    footer += style.Muted.Render("  ·  showing ") + m.tagsView(repo.Todo{Tags: m.tagFilter}, lipgloss.NewStyle())
+  }
+  if m.search != "" && !m.isSearching {
+    footer += style.Muted.Render(fmt.Sprintf("  ·  /%s: %d matches, n/N to jump", m.search, len(m.Svc.Search(m.search))))
+  }
+  if m.err != nil {
+    footer = "\n\n"+style.StatusError.Render("Error: " + m.err.Error())
+  } else if m.notice != "" {
+    footer = "\n\n"+style.ActionStyle.Render(m.notice)
+  }
+  var info []string
+  if list := m.listHeader(); list != "" {
+    info = append(info, list)
+  }
+  if m.query != nil {
+    info = append(info, style.Muted.Render("filter: ") + style.ActionStyle.Render(m.query.String()))
+  }
+  m.Tabs.Info = strings.Join(info, style.Muted.Render("  ·  "))
   tabs := m.Tabs.View()
 
-  content := fmt.Sprintf("%s\n\n%s\n%s", tabs, m.ListViewport.View(), footer)
+  list := m.ListViewport.View()
+  if m.showDetail {
+    list = lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Width(m.ListViewport.Width).Render(list), m.detailView())
+  }
+  content := fmt.Sprintf("%s\n\n%s\n%s", tabs, list, footer)
 
   if m.isDeleting {
     m.confirmationModal.BackgroundView = content
@@ -332,6 +790,21 @@ func (m Model) View() string {
   } else if m.isShowingHelp {
     m.helpModal.BackgroundView = content
     return m.helpModal.View()
+  } else if m.isShowingHistory {
+    m.historyModal.BackgroundView = content
+    return m.historyModal.View()
+  } else if m.backups != nil {
+    m.backupsModal.Body = m.backupsBodyView()
+    m.backupsModal.BackgroundView = content
+    return m.backupsModal.View()
+  } else if m.lists != nil {
+    m.listsModal.Body = m.listsBodyView()
+    m.listsModal.BackgroundView = content
+    return m.listsModal.View()
+  } else if m.notes != nil {
+    m.notesModal.Body = m.notesBodyView()
+    m.notesModal.BackgroundView = content
+    return m.notesModal.View()
   }
 
   return content
@@ -339,7 +812,7 @@ func (m Model) View() string {
 
 func (m Model) ContentView() string {
   var s string
-  todos := m.Svc.Todos(m.Tabs.ActiveIndex == 1)
+  todos := m.todos()
 
   if !m.isAdding && len(todos) == 0 {
     return style.Muted.Render(" No items")
@@ -350,7 +823,13 @@ func (m Model) ContentView() string {
   }
 
   index := 0
-  for _, todo := range todos {
+  now := time.Now()
+  for i, todo := range todos {
+    if m.isGrouped() {
+      if header := m.groupHeader(todos, i, now); header != "" {
+        s += " " + header + "\n"
+      }
+    }
     var itemString string
     itemString, index = m.ItemView(todo, index, "")
     index++
@@ -372,7 +851,7 @@ func (m Model) ItemView(item repo.Todo, index int, padding string) (string, int)
   if item.Done {
     nameStyle.Inherit(style.Muted)
   }
-  if isCurrentRow && !m.isAdding && !m.isAddingChild {
+  if isCurrentRow && !m.isAdding && !m.isAddingChild && !m.isSettingDue && !m.isSettingRepeat {
     outerStyle = style.CheckBoxBracket.Copy().Inherit(style.Highlight)
     innerStyle = style.CheckBox.Copy()
   }
@@ -393,22 +872,24 @@ func (m Model) ItemView(item repo.Todo, index int, padding string) (string, int)
 
   if isCurrentRow {
     if m.Tabs.ActiveIndex == 0 && m.isAdding {
-      s += fmt.Sprintf("%s %s %s", padding, prefix, nameStyle.Render(item.Name))
+      s += fmt.Sprintf("%s %s %s", padding, prefix, m.nameView(item, nameStyle, lipgloss.NewStyle()))
       if !hasChildren {
         s += "\n  " + padding + m.textInput.View()
       }
     } else if m.isEditing {
       s += "  " + padding + m.textInput.View()
-    } else if m.isAddingChild {
-      s += fmt.Sprintf("%s %s %s", padding, prefix, nameStyle.Render(item.Name))
+    } else if m.isAddingChild || m.isSettingDue || m.isSettingRepeat {
+      s += fmt.Sprintf("%s %s %s", padding, prefix, m.nameView(item, nameStyle, lipgloss.NewStyle()))
       s += "\n  " + padding + "   " + m.textInput.View()
     } else {
       prePrefix := style.Highlight.Render(fmt.Sprintf("%s ", padding))
-      postPrefix := style.Highlight.Render(fmt.Sprintf(" %s", nameStyle.Render(item.Name)))
-      s += fmt.Sprintf("%s%s%s", prePrefix, prefix, postPrefix)
+      postPrefix := style.Highlight.Render(" ") + m.nameView(item, nameStyle, style.Highlight)
+      row := fmt.Sprintf("%s%s%s", prePrefix, prefix, postPrefix)
+      s += row + m.rightAligned(row, m.dueView(item), style.Highlight)
     }
   } else {
-    s += fmt.Sprintf("%s %s %s", padding, prefix, nameStyle.Render(item.Name))
+    row := fmt.Sprintf("%s %s %s", padding, prefix, m.nameView(item, nameStyle, lipgloss.NewStyle()))
+    s += row + m.rightAligned(row, m.dueView(item), lipgloss.NewStyle())
   }
 
   s += "\n"
@@ -428,6 +909,185 @@ func (m Model) ItemView(item repo.Todo, index int, padding string) (string, int)
   return s, index
 }
 
+// followItem moves the cursor to the row showing itemId and scrolls the list
+// so that row is visible.
+func (m Model) followItem(todos []repo.Todo, itemId string) Model {
+  row, ok := m.rowOf(todos, itemId, 0)
+  if !ok {
+    return m
+  }
+  m.setCursorRow(row)
+  return m.scrollToLine(m.lineOf(todos, row))
+}
+
+// rowOf is the reverse of itemAtIndex: the row an item is shown on, if it
+// is shown at all.
+func (m Model) rowOf(items []repo.Todo, itemId string, startingAt int) (int, bool) {
+  i := startingAt
+  for _, item := range items {
+    if item.Id == itemId {
+      return i, true
+    }
+    i++
+    if item.Expanded {
+      row, ok := m.rowOf(item.Children, itemId, i)
+      if ok {
+        return row, true
+      }
+      i += m.countRows(item.Children)
+    }
+  }
+  return i, false
+}
+
+// nameView is the name of an item along with its priority, notes indicator
+// and tags, with fill used for the gaps between them.
+func (m Model) nameView(item repo.Todo, nameStyle lipgloss.Style, fill lipgloss.Style) string {
+  s := m.priorityView(item, fill) + m.highlightedName(item, nameStyle, fill)
+  if item.Notes != "" {
+    s += fill.Render(" ") + style.NotesIndicator.Copy().Inherit(fill).Render("✎")
+  }
+  if item.Repeat != "" {
+    s += fill.Render(" ") + style.NotesIndicator.Copy().Inherit(fill).Render("↻")
+  }
+  return s + m.tagsView(item, fill)
+}
+
+// highlightedName renders the name of an item with the letters matching the
+// search picked out.
+func (m Model) highlightedName(item repo.Todo, nameStyle lipgloss.Style, fill lipgloss.Style) string {
+  positions, ok := service.FuzzyMatch(m.search, item.Name)
+  if !ok {
+    return fill.Render(nameStyle.Render(item.Name))
+  }
+
+  matched := map[int]bool{}
+  for _, pos := range positions {
+    matched[pos] = true
+  }
+  matchStyle := style.SearchMatch.Copy().Inherit(fill)
+  var s, run string
+  for i, r := range []rune(item.Name) {
+    if !matched[i] {
+      run += string(r)
+      continue
+    }
+    if run != "" {
+      s += fill.Render(nameStyle.Render(run))
+      run = ""
+    }
+    s += matchStyle.Render(string(r))
+  }
+  if run != "" {
+    s += fill.Render(nameStyle.Render(run))
+  }
+  return s
+}
+
+// jumpToHit moves the cursor to the next item matching the search after
+// from, or the one before it, switching tabs and expanding its ancestors
+// as needed.
+func (m Model) jumpToHit(from *repo.Todo, backwards bool) (Model, tea.Cmd) {
+  fromId := ""
+  if from != nil {
+    fromId = from.Id
+  }
+  hit, ok := m.Svc.NextHit(m.search, fromId, backwards)
+  if !ok {
+    m.notice = "Nothing matches /" + m.search
+    return m, nil
+  }
+
+  tab := 0
+  if hit.Complete {
+    tab = 1
+  }
+  if tab != m.Tabs.ActiveIndex {
+    m.views[m.Tabs.ActiveIndex].offset = m.ListViewport.YOffset
+    m.Tabs.ActiveIndex = tab
+    m.ListViewport.SetYOffset(m.views[tab].offset)
+  }
+  m.followId = hit.Id
+  return m, revealCommand(m.Svc, hit.Id)
+}
+
+// priorityView is the colored marker shown before the name of an item with
+// a priority, with fill used for the space after it.
+func (m Model) priorityView(item repo.Todo, fill lipgloss.Style) string {
+  var marker string
+  var markerStyle lipgloss.Style
+  switch item.Priority {
+  case "":
+    return ""
+  case repo.PriorityHigh:
+    marker, markerStyle = "!!!", style.PriorityHigh
+  case repo.PriorityMedium:
+    marker, markerStyle = "!!", style.PriorityMedium
+  case repo.PriorityLow:
+    marker, markerStyle = "!", style.PriorityLow
+  default:
+    marker, markerStyle = "(" + item.Priority + ")", style.PriorityOther
+  }
+  if item.Done {
+    markerStyle = style.Muted
+  }
+  return markerStyle.Copy().Inherit(fill).Render(marker) + fill.Render(" ")
+}
+
+// tagsView renders the tags of an item as chips, with fill used for the
+// space between them.
+func (m Model) tagsView(item repo.Todo, fill lipgloss.Style) string {
+  var s string
+  for _, tag := range item.Tags {
+    chip := style.Tag
+    if strings.HasPrefix(tag, "@") {
+      chip = style.Mention
+    }
+    if item.Done {
+      chip = chip.Copy().Background(style.Muted.GetForeground())
+    }
+    s += fill.Render(" ") + chip.Render(repo.TagLabel(tag))
+  }
+  return s
+}
+
+// rightAligned pads a row so label ends at the right edge of the list.
+func (m Model) rightAligned(row string, label string, fill lipgloss.Style) string {
+  if label == "" {
+    return ""
+  }
+  gap := m.ListViewport.Width - lipgloss.Width(row) - lipgloss.Width(label) - 1
+  if gap < 1 {
+    gap = 1
+  }
+  return fill.Render(strings.Repeat(" ", gap)) + label
+}
+
+func (m Model) dueView(item repo.Todo) string {
+  due := m.Svc.DueDate(item)
+  date, err := time.ParseInLocation(repo.DateFormat, due, time.Local)
+  if err != nil {
+    return ""
+  }
+
+  now := time.Now()
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+  label := date.Format("Jan 02")
+  if date.Year() != today.Year() {
+    label = due
+  }
+
+  switch {
+  case item.Done:
+    return style.Muted.Render(label)
+  case date.Before(today):
+    return style.Overdue.Render(label)
+  case date.Equal(today):
+    return style.DueToday.Render("today")
+  }
+  return style.Due.Render(label)
+}
+
 func (m Model) helpBodyView() string {
   lines := []string{}
   if (m.Tabs.ActiveIndex == 0) {
@@ -437,10 +1097,36 @@ func (m Model) helpBodyView() string {
 
   lines = append(lines, "c      " + style.ActionStyle.Render("change item"))
   lines = append(lines, "d      " + style.ActionStyle.Render("delete item"))
+  lines = append(lines, "t      " + style.ActionStyle.Render("set due date"))
+  lines = append(lines, "R      " + style.ActionStyle.Render("set repeat rule"))
+  lines = append(lines, "+/-    " + style.ActionStyle.Render("raise/lower priority"))
+  lines = append(lines, "s      " + style.ActionStyle.Render("sort siblings by priority"))
+  lines = append(lines, "T      " + style.ActionStyle.Render("filter by tags"))
+  lines = append(lines, "f      " + style.ActionStyle.Render("filter by query, e.g. tag:backend and not done and due<+7d"))
+  lines = append(lines, "/      " + style.ActionStyle.Render("search"))
+  lines = append(lines, "n/N    " + style.ActionStyle.Render("next/previous match"))
+  lines = append(lines, "e      " + style.ActionStyle.Render("edit notes"))
+  lines = append(lines, "E      " + style.ActionStyle.Render("edit item in $EDITOR"))
+  lines = append(lines, "ctrl+e " + style.ActionStyle.Render("edit subtree in $EDITOR"))
+  lines = append(lines, "v      " + style.ActionStyle.Render("toggle detail pane"))
+  lines = append(lines, "H      " + style.ActionStyle.Render("show item history"))
+  if (m.Tabs.ActiveIndex == 1) {
+    lines = append(lines, "o      " + style.ActionStyle.Render("sort/group by completion date"))
+  }
+  lines = append(lines, "S      " + style.ActionStyle.Render("sort all by priority"))
   lines = append(lines, "space  " + style.ActionStyle.Render("toggle item"))
   lines = append(lines, "j      " + style.ActionStyle.Render("move down"))
   lines = append(lines, "k      " + style.ActionStyle.Render("move up"))
+  lines = append(lines, "K/J    " + style.ActionStyle.Render("move item up/down"))
+  lines = append(lines, "y/x    " + style.ActionStyle.Render("yank/cut item"))
+  lines = append(lines, "p/P    " + style.ActionStyle.Render("paste after/as child of item"))
+  lines = append(lines, ">/<    " + style.ActionStyle.Render("indent/outdent item (or tab/shift+tab)"))
   lines = append(lines, "W      " + style.ActionStyle.Render("collapse all"))
+  lines = append(lines, "u      " + style.ActionStyle.Render("undo"))
+  lines = append(lines, "ctrl+r " + style.ActionStyle.Render("redo"))
+  lines = append(lines, "L      " + style.ActionStyle.Render("switch list"))
+  lines = append(lines, "M      " + style.ActionStyle.Render("move item to another list"))
+  lines = append(lines, "B      " + style.ActionStyle.Render("browse backups"))
   lines = append(lines, "G      " + style.ActionStyle.Render("go to bottom"))
   lines = append(lines, "g      " + style.ActionStyle.Render("go to top"))
   lines = append(lines, "]      " + style.ActionStyle.Render("next tab"))
@@ -486,56 +1172,208 @@ func (m Model) itemAtIndex(items []repo.Todo, index int, startingAt int) (*repo.
 
 func addTodoCommand(service *service.Service, afterItem *repo.Todo, name string) tea.Cmd {
   return func() tea.Msg {
-    service.AddTodo(afterItem, name)
+    if err := service.AddTodo(afterItem, name); err != nil {
+      return errMsg{event: "todo-added", err: err}
+    }
     return "todo-added"
   }
 }
 
 func addTodoAsChildCommand(service *service.Service, parent *repo.Todo, name string) tea.Cmd {
   return func() tea.Msg {
-    service.AddTodoAsChild(parent, name)
+    if err := service.AddTodoAsChild(parent, name); err != nil {
+      return errMsg{event: "todo-child-added", err: err}
+    }
     return "todo-child-added"
   }
 }
 
 func changeTodoCommand(service *service.Service, item repo.Todo, name string) tea.Cmd {
   return func() tea.Msg {
-    service.ChangeTodo(item, name)
+    if err := service.ChangeTodo(item, name); err != nil {
+      return errMsg{event: "todo-changed", err: err}
+    }
     return "todo-changed"
   }
 }
 
 func toggleTodoCommand(service *service.Service, item repo.Todo) tea.Cmd {
   return func() tea.Msg {
-    service.ToggleTodo(item)
+    if err := service.ToggleTodo(item); err != nil {
+      return errMsg{event: "todo-toggled", err: err}
+    }
     return "todo-toggled"
   }
 }
 
 func toggleExpandedCommand(service *service.Service, item repo.Todo) tea.Cmd {
   return func() tea.Msg {
-    service.ToggleExpanded(item)
+    if err := service.ToggleExpanded(item); err != nil {
+      return errMsg{event: "todo-expand-toggled", err: err}
+    }
     return "todo-expand-toggled"
   }
 }
 
+func setDueCommand(service *service.Service, item repo.Todo, due string) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.SetDue(item, due); err != nil {
+      return errMsg{event: "todo-due-set", err: err}
+    }
+    return "todo-due-set"
+  }
+}
+
+func raisePriorityCommand(service *service.Service, item repo.Todo) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.RaisePriority(item); err != nil {
+      return errMsg{event: "todo-priority-changed", err: err}
+    }
+    return "todo-priority-changed"
+  }
+}
+
+func lowerPriorityCommand(service *service.Service, item repo.Todo) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.LowerPriority(item); err != nil {
+      return errMsg{event: "todo-priority-changed", err: err}
+    }
+    return "todo-priority-changed"
+  }
+}
+
+func sortByPriorityCommand(service *service.Service, item *repo.Todo) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.SortByPriority(item); err != nil {
+      return errMsg{event: "todos-sorted", err: err}
+    }
+    return "todos-sorted"
+  }
+}
+
+func setRepeatCommand(service *service.Service, item repo.Todo, rule string) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.SetRepeat(item, rule); err != nil {
+      return errMsg{event: "todo-repeat-set", err: err}
+    }
+    return "todo-repeat-set"
+  }
+}
+
+func moveCommand(service *service.Service, item repo.Todo, down bool) tea.Cmd {
+  return func() tea.Msg {
+    move := service.MoveUp
+    if down {
+      move = service.MoveDown
+    }
+    if err := move(item); err != nil {
+      return errMsg{event: "todo-moved", err: err}
+    }
+    return "todo-moved"
+  }
+}
+
+func indentCommand(service *service.Service, item repo.Todo, outdent bool) tea.Cmd {
+  return func() tea.Msg {
+    indent := service.Indent
+    if outdent {
+      indent = service.Outdent
+    }
+    if err := indent(item); err != nil {
+      return errMsg{event: "todo-moved", err: err}
+    }
+    return "todo-moved"
+  }
+}
+
+func cutCommand(service *service.Service, itemId string) tea.Cmd {
+  return func() tea.Msg {
+    item, err := service.Cut(itemId)
+    return cutMsg{item: item, err: err}
+  }
+}
+
+func pasteCommand(service *service.Service, target *repo.Todo, item repo.Todo, asChild, copy bool) tea.Cmd {
+  return func() tea.Msg {
+    itemId, err := service.Paste(target, item, asChild, copy)
+    return pastedMsg{itemId: itemId, err: err}
+  }
+}
+
+func revealCommand(service *service.Service, itemId string) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.Reveal(itemId); err != nil {
+      return errMsg{event: "todo-revealed", err: err}
+    }
+    return "todo-revealed"
+  }
+}
+
 func deleteTodoCommand(service *service.Service, item repo.Todo) tea.Cmd {
   return func() tea.Msg {
-    service.DeleteTodo(item)
+    if err := service.DeleteTodo(item); err != nil {
+      return errMsg{event: "todo-deleted", err: err}
+    }
     return "todo-deleted"
   }
 }
 
+func undoCommand(service *service.Service, redo bool) tea.Cmd {
+  return func() tea.Msg {
+    undo := service.Undo
+    if redo {
+      undo = service.Redo
+    }
+    label, err := undo()
+    if err != nil {
+      return errMsg{event: "todos-undone", err: err}
+    }
+    return undoMsg{label: label, redo: redo}
+  }
+}
+
+func watchCommand(service *service.Service) tea.Cmd {
+  changes := service.Watch()
+  if changes == nil {
+    return nil
+  }
+  return func() tea.Msg {
+    <-changes
+    return externalChangeMsg{}
+  }
+}
+
 func collapseAllCommand(service *service.Service, completed bool) tea.Cmd {
   return func() tea.Msg {
-    service.CollapseAll(completed)
+    if err := service.CollapseAll(completed); err != nil {
+      return errMsg{event: "todos-collapsed", err: err}
+    }
     return "todos-collapsed"
   }
 }
 
 func main() {
-  p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-  if _, err := p.Run(); err != nil {
+  subcommands := map[string]func([]string) error{
+    "backups": runBackups,
+    "export": runExport,
+    "import": runImport,
+    "list": runList,
+  }
+  if len(os.Args) > 1 && subcommands[os.Args[1]] != nil {
+    if err := subcommands[os.Args[1]](os.Args[2:]); err != nil {
+      fmt.Fprintln(os.Stderr, err)
+      os.Exit(1)
+    }
+    return
+  }
+
+  flag.Parse()
+  p := tea.NewProgram(initialModel(todoFilename(flag.Args())), tea.WithAltScreen())
+  model, err := p.Run()
+  if m, ok := model.(Model); ok && m.Svc != nil {
+    m.Svc.Close()
+  }
+  if err != nil {
     fmt.Printf("Alas, there's been an error: %v", err)
     os.Exit(1)
   }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+	"github.com/jquag/tui-do/style"
+)
+
+// notesEditor is the state of the notes modal.
+type notesEditor struct {
+  item repo.Todo
+  input textarea.Model
+}
+
+func (m Model) openNotes(item repo.Todo) (Model, tea.Cmd) {
+  input := textarea.New()
+  input.CharLimit = 0
+  input.ShowLineNumbers = false
+  input.Placeholder = "Notes, links, acceptance criteria... markdown works"
+  input.SetWidth(clamp(m.width - 12, 20, 72))
+  input.SetHeight(clamp(m.height - 12, 3, 12))
+  input.SetValue(item.Notes)
+  cmd := input.Focus()
+
+  m.notes = &notesEditor{item: item, input: input}
+  m.notesModal.Title = "Notes: " + item.Name
+  return m, cmd
+}
+
+func (m Model) updateNotes(msg tea.Msg) (Model, tea.Cmd) {
+  n := *m.notes
+  m.notes = &n
+
+  if msg, ok := msg.(tea.KeyMsg); ok {
+    switch msg.String() {
+      case "ctrl+c":
+        return m, tea.Quit
+
+      case tea.KeyEscape.String():
+        m.notes = nil
+        return m, nil
+
+      case "ctrl+s":
+        m.notes = nil
+        return m, setNotesCommand(m.Svc, n.item, n.input.Value())
+    }
+  }
+
+  var cmd tea.Cmd
+  n.input, cmd = n.input.Update(msg)
+  return m, cmd
+}
+
+func (m Model) notesBodyView() string {
+  return "\n" + m.notes.input.View() + "\n\n" + style.Muted.Render("CTRL+S-save, ESC-cancel")
+}
+
+// detailWidth is the width of the detail pane, including its border.
+func (m Model) detailWidth() int {
+  return clamp(m.width * 2 / 5, 24, 80)
+}
+
+// listWidth is what's left of the screen for the list.
+func (m Model) listWidth() int {
+  if m.showDetail {
+    return m.width - m.detailWidth()
+  }
+  return m.width
+}
+
+func (m Model) detailView() string {
+  width := m.detailWidth() - 2
+  pane := style.DetailPane.Copy().Width(width + 1).Height(m.ListViewport.Height).MaxHeight(m.ListViewport.Height)
+
+  item, _ := m.itemAtIndex(m.todos(), m.cursorRow(), 0)
+  if item == nil {
+    return pane.Render(style.Muted.Render("Nothing selected"))
+  }
+
+  lines := []string{style.ParentColor.Copy().Bold(true).Width(width).Render(item.Name)}
+  if tags := m.tagsView(*item, lipgloss.NewStyle()); tags != "" {
+    lines = append(lines, strings.TrimPrefix(tags, " "))
+  }
+  lines = append(lines, "")
+  if item.Notes == "" {
+    lines = append(lines, style.Muted.Render("No notes, press e to add some"))
+  } else {
+    lines = append(lines, renderMarkdown(item.Notes, width))
+  }
+  return pane.Render(strings.Join(lines, "\n"))
+}
+
+var markdownRenderer struct {
+  width int
+  renderer *glamour.TermRenderer
+}
+
+// renderMarkdown renders text for the terminal, falling back to the plain
+// text if it can't.
+func renderMarkdown(text string, width int) string {
+  if markdownRenderer.renderer == nil || markdownRenderer.width != width {
+    r, err := glamour.NewTermRenderer(glamour.WithStandardStyle("dark"), glamour.WithWordWrap(width - 2))
+    if err != nil {
+      return text
+    }
+    markdownRenderer.width = width
+    markdownRenderer.renderer = r
+  }
+
+  out, err := markdownRenderer.renderer.Render(text)
+  if err != nil {
+    return text
+  }
+  return strings.Trim(out, "\n")
+}
+
+func clamp(n, min, max int) int {
+  if n < min {
+    return min
+  }
+  if n > max {
+    return max
+  }
+  return n
+}
+
+func setNotesCommand(service *service.Service, item repo.Todo, notes string) tea.Cmd {
+  return func() tea.Msg {
+    if err := service.SetNotes(item, notes); err != nil {
+      return errMsg{event: "todo-notes-set", err: err}
+    }
+    return "todo-notes-set"
+  }
+}
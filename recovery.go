@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jquag/tui-do/repo"
+	"github.com/jquag/tui-do/service"
+	"github.com/jquag/tui-do/style"
+)
+
+// recovery is the state of the screen shown instead of the list when the
+// todo file can't be loaded.
+type recovery struct {
+  filename string
+  // workspace is the directory filename is a list of, when none of its
+  // lists could be loaded
+  workspace string
+  err error
+  backup string
+  notice string
+}
+
+type editorClosedMsg struct {
+  err error
+}
+
+func newRecovery(filename string, err error) *recovery {
+  backup, _ := repo.LatestBackup(filename)
+  return &recovery{
+    filename: filename,
+    err: err,
+    backup: backup,
+  }
+}
+
+// editorCommand builds the command that opens path in the user's editor.
+func editorCommand(path string) *exec.Cmd {
+  editor := os.Getenv("VISUAL")
+  if editor == "" {
+    editor = os.Getenv("EDITOR")
+  }
+  if editor == "" {
+    editor = "vi"
+  }
+  args := strings.Fields(editor)
+  return exec.Command(args[0], append(args[1:], path)...)
+}
+
+func (m Model) updateRecovery(msg tea.Msg) (tea.Model, tea.Cmd) {
+  switch msg := msg.(type) {
+  case tea.WindowSizeMsg:
+    m.width = msg.Width
+    m.height = msg.Height
+
+  case tea.KeyMsg:
+    switch msg.String() {
+      case "ctrl+c", "q":
+        return m, tea.Quit
+
+      case "e":
+        return m, tea.ExecProcess(editorCommand(m.recovery.filename), func(err error) tea.Msg {
+          return editorClosedMsg{err}
+        })
+
+      case "r":
+        if m.recovery.backup != "" {
+          aside, err := repo.MoveAside(m.recovery.filename)
+          if err != nil {
+            m.recovery.notice = "Could not move the file aside: " + err.Error()
+            return m, nil
+          }
+          m.recovery.notice = "Moved the broken file to " + aside
+          store, err := repo.OpenStore(m.recovery.filename, *format)
+          if err == nil {
+            err = repo.RestoreBackup(store, m.recovery.backup)
+            if closer, ok := store.(io.Closer); ok {
+              closer.Close()
+            }
+          }
+          if err != nil {
+            m.recovery.notice += ", but could not restore backup: " + err.Error()
+            return m, nil
+          }
+          return m.retryLoad()
+        }
+
+      case "n":
+        aside, err := repo.MoveAside(m.recovery.filename)
+        if err != nil {
+          m.recovery.notice = "Could not move the file aside: " + err.Error()
+          return m, nil
+        }
+        m.recovery.notice = "Moved the broken file to " + aside
+        return m.retryLoad()
+    }
+
+  case editorClosedMsg:
+    if msg.err != nil {
+      m.recovery.notice = "Editor failed: " + msg.err.Error()
+      return m, nil
+    }
+    return m.retryLoad()
+  }
+
+  return m, nil
+}
+
+// retryLoad loads the todo file, or the whole workspace it's a list of,
+// again and, if that works, leaves the recovery screen for the regular
+// list.
+func (m Model) retryLoad() (tea.Model, tea.Cmd) {
+  filename := m.recovery.filename
+  var svc *service.Service
+  var err error
+  if m.recovery.workspace != "" {
+    var failed []string
+    svc, failed, err = openWorkspace(m.recovery.workspace)
+    if svc == nil && len(failed) > 0 {
+      filename = failed[0]
+    }
+  } else {
+    var r *repo.Repo
+    if r, err = openRepo(filename); err == nil {
+      svc = service.NewService(r)
+    }
+  }
+  if svc == nil {
+    notice, workspace := m.recovery.notice, m.recovery.workspace
+    m.recovery = newRecovery(filename, err)
+    m.recovery.notice, m.recovery.workspace = notice, workspace
+    return m, nil
+  }
+
+  m.Svc = svc
+  m.err = err
+  m.recovery = nil
+  if m.width == 0 {
+    return m, watchCommand(m.Svc)
+  }
+  model, cmd := m.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+  return model, tea.Batch(cmd, watchCommand(m.Svc))
+}
+
+func (m Model) recoveryView() string {
+  var loadErr *repo.LoadError
+  var problem string
+  if errors.As(m.recovery.err, &loadErr) && loadErr.Line > 0 {
+    problem = fmt.Sprintf("%s is not valid at line %d, column %d:\n  %v", loadErr.Filename, loadErr.Line, loadErr.Column, loadErr.Err)
+  } else {
+    problem = fmt.Sprintf("%s could not be loaded:\n  %v", m.recovery.filename, m.recovery.err)
+  }
+
+  lines := []string{
+    style.ModalTitle.Render("Unable to load todos"),
+    "",
+    style.StatusError.Render(problem),
+    "",
+    "e      " + style.ActionStyle.Render("open the file in $EDITOR"),
+  }
+  if m.recovery.backup != "" {
+    lines = append(lines, "r      " + style.ActionStyle.Render("move the file aside and restore backup " + m.recovery.backup))
+  } else {
+    lines = append(lines, style.Muted.Render("r      no automatic backup available"))
+  }
+  lines = append(lines, "n      " + style.ActionStyle.Render("move the file aside and start an empty list"))
+  lines = append(lines, "q      " + style.ActionStyle.Render("quit"))
+
+  if m.recovery.notice != "" {
+    lines = append(lines, "", style.Muted.Render(m.recovery.notice))
+  }
+
+  return style.Card.Render("\n" + strings.Join(lines, "\n"))
+}
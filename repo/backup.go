@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backups are named after the time they were taken down to the
+// millisecond. Parsing with backupTimeFormat accepts the milliseconds too,
+// as well as names from before they were added.
+const (
+  backupTimeFormat = "2006-01-02T15-04-05"
+  backupNameFormat = backupTimeFormat + ".000"
+)
+
+const (
+  DefaultBackupKeep = 20
+  DefaultBackupInterval = 10 * time.Minute
+)
+
+// BackupPolicy controls the snapshots a Repo takes of its todos as it
+// persists them. At most one snapshot is taken per Interval and only the
+// newest Keep snapshots are kept in Dir.
+type BackupPolicy struct {
+  Dir string
+  Keep int
+  Interval time.Duration
+}
+
+// Backup is one snapshot in a backup directory.
+type Backup struct {
+  Path string
+  Time time.Time
+}
+
+func (b Backup) Name() string {
+  return strings.TrimSuffix(filepath.Base(b.Path), ".json")
+}
+
+// BackupDir is the directory holding automatic backups of filename.
+func BackupDir(filename string) string {
+  return filename + ".d"
+}
+
+// ListBackups returns the snapshots in dir, newest first.
+func ListBackups(dir string) ([]Backup, error) {
+  matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+  if err != nil {
+    return nil, err
+  }
+
+  var backups []Backup
+  for _, path := range matches {
+    name := strings.TrimSuffix(filepath.Base(path), ".json")
+    t, err := time.ParseInLocation(backupTimeFormat, name, time.Local)
+    if err != nil {
+      continue
+    }
+    backups = append(backups, Backup{Path: path, Time: t})
+  }
+
+  sort.Slice(backups, func(i, j int) bool {
+    return backups[i].Time.After(backups[j].Time)
+  })
+  return backups, nil
+}
+
+// LoadBackup reads the todos stored in a snapshot.
+func LoadBackup(path string) ([]Todo, error) {
+  content, err := os.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  todos, err := JSONCodec{}.Decode(content)
+  if err != nil {
+    return nil, newLoadError(path, content, err)
+  }
+  return todos, nil
+}
+
+func writeBackup(policy BackupPolicy, todos []Todo, now time.Time) error {
+  if err := os.MkdirAll(policy.Dir, 0755); err != nil {
+    return err
+  }
+
+  content, err := JSONCodec{}.Encode(todos)
+  if err != nil {
+    return err
+  }
+  path := filepath.Join(policy.Dir, now.Format(backupNameFormat) + ".json")
+  for {
+    // two snapshots in the same millisecond, like the one Restore takes
+    // before a save, must not overwrite each other
+    if _, err := os.Stat(path); err != nil {
+      break
+    }
+    now = now.Add(time.Millisecond)
+    path = filepath.Join(policy.Dir, now.Format(backupNameFormat) + ".json")
+  }
+  if err := writeFileAtomic(path, content, 0644); err != nil {
+    return err
+  }
+
+  backups, err := ListBackups(policy.Dir)
+  if err != nil {
+    return err
+  }
+  for i := policy.Keep; i < len(backups); i++ {
+    os.Remove(backups[i].Path)
+  }
+  return nil
+}
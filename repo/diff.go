@@ -0,0 +1,128 @@
+package repo
+
+import "strings"
+
+type DiffOp int
+
+const (
+  Same DiffOp = iota
+  Added
+  Removed
+)
+
+// DiffLine is one line of an outline diff between two lists of todos.
+type DiffLine struct {
+  Op DiffOp
+  Text string
+}
+
+func (l DiffLine) String() string {
+  switch l.Op {
+  case Added:
+    return "+ " + l.Text
+  case Removed:
+    return "- " + l.Text
+  }
+  return "  " + l.Text
+}
+
+// Outline renders todos as an indented checklist, one line per item.
+func Outline(todos []Todo) []string {
+  var lines []string
+  var add func(todos []Todo, depth int)
+  add = func(todos []Todo, depth int) {
+    for _, t := range todos {
+      check := "[ ]"
+      if t.Done {
+        check = "[x]"
+      }
+      lines = append(lines, strings.Repeat("  ", depth) + check + " " + t.Name)
+      add(t.Children, depth+1)
+    }
+  }
+  add(todos, 0)
+  return lines
+}
+
+// past this many cells for the lines in between the common prefix and
+// suffix, Diff doesn't look for lines they share and shows them all as
+// removed and added instead
+const diffLimit = 1 << 20
+
+// Diff compares the outlines of from and to line by line.
+func Diff(from, to []Todo) []DiffLine {
+  a, b := Outline(from), Outline(to)
+
+  // only the lines between the common prefix and suffix need comparing
+  prefix := 0
+  for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+    prefix++
+  }
+  suffix := 0
+  for suffix < len(a) - prefix && suffix < len(b) - prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+    suffix++
+  }
+
+  var lines []DiffLine
+  for _, line := range a[:prefix] {
+    lines = append(lines, DiffLine{Same, line})
+  }
+  lines = append(lines, diffLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+  for _, line := range a[len(a)-suffix:] {
+    lines = append(lines, DiffLine{Same, line})
+  }
+  return lines
+}
+
+// diffLines compares a and b using their longest common subsequence.
+func diffLines(a, b []string) []DiffLine {
+  var lines []DiffLine
+  if (len(a) + 1) * (len(b) + 1) > diffLimit {
+    for _, line := range a {
+      lines = append(lines, DiffLine{Removed, line})
+    }
+    for _, line := range b {
+      lines = append(lines, DiffLine{Added, line})
+    }
+    return lines
+  }
+
+  // lcs[i][j] is the length of the longest common subsequence of a[i:], b[j:]
+  lcs := make([][]int, len(a)+1)
+  for i := range lcs {
+    lcs[i] = make([]int, len(b)+1)
+  }
+  for i := len(a) - 1; i >= 0; i-- {
+    for j := len(b) - 1; j >= 0; j-- {
+      if a[i] == b[j] {
+        lcs[i][j] = lcs[i+1][j+1] + 1
+      } else if lcs[i+1][j] >= lcs[i][j+1] {
+        lcs[i][j] = lcs[i+1][j]
+      } else {
+        lcs[i][j] = lcs[i][j+1]
+      }
+    }
+  }
+
+  i, j := 0, 0
+  for i < len(a) && j < len(b) {
+    if a[i] == b[j] {
+      lines = append(lines, DiffLine{Same, a[i]})
+      i++
+      j++
+    } else if lcs[i+1][j] >= lcs[i][j+1] {
+      lines = append(lines, DiffLine{Removed, a[i]})
+      i++
+    } else {
+      lines = append(lines, DiffLine{Added, b[j]})
+      j++
+    }
+  }
+  for ; i < len(a); i++ {
+    lines = append(lines, DiffLine{Removed, a[i]})
+  }
+  for ; j < len(b); j++ {
+    lines = append(lines, DiffLine{Added, b[j]})
+  }
+  return lines
+}
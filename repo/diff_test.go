@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// diffText renders a diff as its lines joined by "|".
+func diffText(lines []DiffLine) string {
+  var parts []string
+  for _, l := range lines {
+    parts = append(parts, l.String())
+  }
+  return strings.Join(parts, "|")
+}
+
+func TestDiff(t *testing.T) {
+  tests := []struct {
+    name string
+    from, to []Todo
+    want string
+  }{
+    {"both empty", nil, nil, ""},
+    {"same", []Todo{todo("a"), todo("b")}, []Todo{todo("a"), todo("b")}, "  [ ] a|  [ ] b"},
+    {"added", nil, []Todo{todo("a")}, "+ [ ] a"},
+    {"removed", []Todo{todo("a")}, nil, "- [ ] a"},
+    {
+      "changed in the middle",
+      []Todo{todo("a"), todo("b"), todo("c")},
+      []Todo{todo("a"), todo("x"), todo("c")},
+      "  [ ] a|- [ ] b|+ [ ] x|  [ ] c",
+    },
+    {
+      "nested",
+      []Todo{todo("a", todo("b")), todo("c")},
+      []Todo{todo("a", todo("b"), todo("d")), todo("c")},
+      "  [ ] a|    [ ] b|+   [ ] d|  [ ] c",
+    },
+    {
+      "moved",
+      []Todo{todo("a"), todo("b"), todo("c")},
+      []Todo{todo("b"), todo("c"), todo("a")},
+      "- [ ] a|  [ ] b|  [ ] c|+ [ ] a",
+    },
+    {
+      "repeated lines",
+      []Todo{todo("a"), todo("a")},
+      []Todo{todo("a"), todo("a"), todo("a")},
+      "  [ ] a|  [ ] a|+ [ ] a",
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := diffText(Diff(tt.from, tt.to)); got != tt.want {
+        t.Errorf("diff is %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestDiffLargeLists(t *testing.T) {
+  var from []Todo
+  for i := 0; i < 5000; i++ {
+    from = append(from, todo(fmt.Sprint(i)))
+  }
+  to := cloneTodos(from)
+  to[2500].Name = "changed"
+
+  lines := Diff(from, to)
+  var changed []DiffLine
+  for _, l := range lines {
+    if l.Op != Same {
+      changed = append(changed, l)
+    }
+  }
+  want := []DiffLine{{Removed, "[ ] 2500"}, {Added, "[ ] changed"}}
+  if len(lines) != 5001 || !reflect.DeepEqual(changed, want) {
+    t.Errorf("diff has %d lines changing %v, want 5001 changing %v", len(lines), changed, want)
+  }
+
+  // rewriting everything gives up on finding common lines
+  for i := range to {
+    to[i].Name += "!"
+  }
+  if lines := Diff(from, to); len(lines) != 10000 || lines[0].Op != Removed || lines[9999].Op != Added {
+    t.Errorf("rewriting every line gave %d lines", len(lines))
+  }
+}
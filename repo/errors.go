@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LoadError is returned when a todo file exists but can't be decoded.
+// Line and Column are 1-based and zero when the position is unknown.
+type LoadError struct {
+  Filename string
+  Line int
+  Column int
+  Err error
+}
+
+func (e *LoadError) Error() string {
+  if e.Line == 0 {
+    return fmt.Sprintf("%s: %v", e.Filename, e.Err)
+  }
+  return fmt.Sprintf("%s:%d:%d: %v", e.Filename, e.Line, e.Column, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+  return e.Err
+}
+
+func newLoadError(filename string, content []byte, err error) *LoadError {
+  loadErr := &LoadError{Filename: filename, Err: err}
+
+  offset := int64(-1)
+  var syntaxErr *json.SyntaxError
+  var typeErr *json.UnmarshalTypeError
+  if errors.As(err, &syntaxErr) {
+    offset = syntaxErr.Offset
+  } else if errors.As(err, &typeErr) {
+    offset = typeErr.Offset
+  }
+
+  // offsets point just past the offending byte
+  offset--
+  if offset >= 0 && offset < int64(len(content)) {
+    before := content[:offset]
+    loadErr.Line = bytes.Count(before, []byte("\n")) + 1
+    loadErr.Column = len(before) - bytes.LastIndexByte(before, '\n')
+  }
+  return loadErr
+}
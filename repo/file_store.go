@@ -0,0 +1,254 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const watchInterval = time.Second
+
+// ErrConflict is returned by Save when the file was changed by someone else
+// since this store last read or wrote it.
+var ErrConflict = errors.New("file was changed by another process")
+
+// FileStore keeps todos in a single file, encoded with a Codec. Reads and
+// writes hold an advisory lock on a sibling ".lock" file so several
+// processes can share one todo file.
+type FileStore struct {
+  filename string
+  codec Codec
+
+  mu sync.Mutex
+  // seen is the version of the file this store last read or wrote
+  seen os.FileInfo
+  polled os.FileInfo
+  watch chan struct{}
+  // done stops the polling Watch started
+  done chan struct{}
+}
+
+func NewFileStore(filename string, codec Codec) *FileStore {
+  return &FileStore{
+    filename: filename,
+    codec: codec,
+  }
+}
+
+func (f *FileStore) Load() ([]Todo, error) {
+  var content []byte
+  err := f.withLock(false, func() error {
+    var err error
+    content, err = os.ReadFile(f.filename)
+    if err == nil {
+      f.rememberVersion()
+    }
+    return err
+  })
+  if os.IsNotExist(err) {
+    if err := f.Save(nil); err != nil {
+      return nil, err
+    }
+    return nil, nil
+  } else if err != nil {
+    return nil, err
+  }
+
+  if migrator, ok := f.codec.(Migrator); ok {
+    migrated, changed, err := migrator.Migrate(content)
+    if err != nil {
+      return nil, newLoadError(f.filename, content, err)
+    }
+    if changed {
+      if err := f.upgrade(content, migrated); err != nil {
+        return nil, err
+      }
+      content = migrated
+    }
+  }
+
+  todos, err := f.codec.Decode(content)
+  if err != nil {
+    return nil, newLoadError(f.filename, content, err)
+  }
+  return todos, nil
+}
+
+// upgrade replaces an old format file with its migrated content, keeping
+// the original next to it as a ".bak" file.
+func (f *FileStore) upgrade(original, migrated []byte) error {
+  return f.withLock(true, func() error {
+    if err := writeFileAtomic(f.filename + ".bak", original, 0644); err != nil {
+      return err
+    }
+    return f.write(migrated)
+  })
+}
+
+// Save writes the todos to a temp file next to the real one, syncs it and
+// renames it into place so a crash mid-write never leaves a truncated file.
+// It refuses with ErrConflict if the file changed since it was last loaded,
+// unless this store never read it at all.
+func (f *FileStore) Save(todos []Todo) error {
+  content, err := f.codec.Encode(todos)
+  if err != nil {
+    return err
+  }
+
+  return f.withLock(true, func() error {
+    if info, err := os.Stat(f.filename); err == nil {
+      f.mu.Lock()
+      changed := f.seen != nil && !sameVersion(info, f.seen)
+      f.mu.Unlock()
+      if changed {
+        return ErrConflict
+      }
+    }
+    return f.write(content)
+  })
+}
+
+// write replaces the file with content and remembers the new version. Both
+// happen under f.mu, so poll can't mistake the write for someone else's.
+func (f *FileStore) write(content []byte) error {
+  f.mu.Lock()
+  defer f.mu.Unlock()
+
+  err := writeFileAtomic(f.filename, content, 0644)
+  if info, statErr := os.Stat(f.filename); statErr == nil {
+    f.seen = info
+  }
+  return err
+}
+
+// Watch polls the modification time of the file. Writes made through this
+// FileStore are not reported.
+func (f *FileStore) Watch() <-chan struct{} {
+  f.mu.Lock()
+  defer f.mu.Unlock()
+
+  if f.watch == nil {
+    f.watch = make(chan struct{}, 1)
+    f.done = make(chan struct{})
+    go f.poll(f.watch, f.done)
+  }
+  return f.watch
+}
+
+// Close stops watching the file. The store can still be loaded and saved.
+func (f *FileStore) Close() error {
+  f.mu.Lock()
+  defer f.mu.Unlock()
+
+  if f.done != nil {
+    close(f.done)
+    f.watch, f.done = nil, nil
+  }
+  return nil
+}
+
+func (f *FileStore) poll(watch chan struct{}, done chan struct{}) {
+  ticker := time.NewTicker(watchInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-done:
+      return
+    case <-ticker.C:
+    }
+
+    f.mu.Lock()
+    info, err := os.Stat(f.filename)
+    changed := err == nil && !sameVersion(info, f.seen) && !sameVersion(info, f.polled)
+    if err == nil {
+      f.polled = info
+    }
+    f.mu.Unlock()
+
+    if changed {
+      select {
+      case watch <- struct{}{}:
+      default:
+      }
+    }
+  }
+}
+
+func (f *FileStore) rememberVersion() {
+  info, err := os.Stat(f.filename)
+  if err != nil {
+    return
+  }
+  f.mu.Lock()
+  f.seen = info
+  f.mu.Unlock()
+}
+
+// sameVersion compares the inode as well as size and mtime because mtimes
+// are too coarse to tell apart two quick writes.
+func sameVersion(a, b os.FileInfo) bool {
+  if a == nil || b == nil {
+    return a == b
+  }
+  return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// withLock runs fn while holding the lock file next to the todo file. The
+// todo file itself can't be locked because Save replaces it on every write.
+func (f *FileStore) withLock(exclusive bool, fn func() error) error {
+  lock, err := os.OpenFile(f.filename + ".lock", os.O_RDWR|os.O_CREATE, 0644)
+  if err != nil {
+    return err
+  }
+  defer lock.Close()
+
+  if err := lockFile(lock, exclusive); err != nil {
+    return err
+  }
+  defer unlockFile(lock)
+
+  return fn()
+}
+
+func writeFileAtomic(filename string, content []byte, perm os.FileMode) error {
+  dir, base := filepath.Split(filename)
+  if dir == "" {
+    dir = "."
+  }
+  if info, err := os.Stat(filename); err == nil {
+    perm = info.Mode().Perm()
+  }
+
+  tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+  if err != nil {
+    return err
+  }
+  defer os.Remove(tmp.Name())
+
+  if _, err := tmp.Write(content); err != nil {
+    tmp.Close()
+    return err
+  }
+  if err := tmp.Sync(); err != nil {
+    tmp.Close()
+    return err
+  }
+  if err := tmp.Close(); err != nil {
+    return err
+  }
+  if err := os.Chmod(tmp.Name(), perm); err != nil {
+    return err
+  }
+  if err := os.Rename(tmp.Name(), filename); err != nil {
+    return err
+  }
+
+  // make the rename itself durable
+  if d, err := os.Open(dir); err == nil {
+    d.Sync()
+    d.Close()
+  }
+  return nil
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreMigratesLegacyFile(t *testing.T) {
+  filename := filepath.Join(t.TempDir(), "todos.json")
+  legacy := `[{"Id":"1","Name":"a","Done":false,"Expanded":true,"Children":[{"Id":"2","Name":"b","Done":true,"Expanded":false,"Children":null}]}]`
+  if err := os.WriteFile(filename, []byte(legacy), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  f := NewFileStore(filename, JSONCodec{})
+  defer f.Close()
+  todos, err := f.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(todos); got != "a(x:b)" {
+    t.Errorf("loaded %q, want %q", got, "a(x:b)")
+  }
+
+  content, err := os.ReadFile(filename)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var doc document
+  if err := json.Unmarshal(content, &doc); err != nil {
+    t.Fatalf("the file wasn't rewritten as a document: %v\n%s", err, content)
+  }
+  if doc.Version != CurrentVersion {
+    t.Errorf("the file has version %d, want %d", doc.Version, CurrentVersion)
+  }
+  var stored []Todo
+  if err := json.Unmarshal(doc.Todos, &stored); err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(stored); got != "a(x:b)" {
+    t.Errorf("the file holds %q, want %q", got, "a(x:b)")
+  }
+
+  backup, err := os.ReadFile(filename + ".bak")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if string(backup) != legacy {
+    t.Errorf("the backup holds\n%s\nwant\n%s", backup, legacy)
+  }
+
+  // loading the migrated file leaves it and the backup alone
+  if _, err := NewFileStore(filename, JSONCodec{}).Load(); err != nil {
+    t.Fatal(err)
+  }
+  if again, _ := os.ReadFile(filename); string(again) != string(content) {
+    t.Errorf("loading again rewrote the file as\n%s", again)
+  }
+  if again, _ := os.ReadFile(filename + ".bak"); string(again) != legacy {
+    t.Errorf("loading again rewrote the backup as\n%s", again)
+  }
+}
@@ -0,0 +1,56 @@
+package repo
+
+import "encoding/json"
+
+// JSONCodec is the default codec. It stores todos in a versioned document,
+// {"version": N, "todos": [...]}, upgrading older files as they are loaded.
+type JSONCodec struct{}
+
+func (JSONCodec) Decode(content []byte) ([]Todo, error) {
+  doc, err := parseDocument(content)
+  if err != nil {
+    return nil, err
+  }
+  if doc.Version == CurrentVersion {
+    // decode the whole content so error offsets match the file
+    var current struct {
+      Todos []Todo `json:"todos"`
+    }
+    err = json.Unmarshal(content, &current)
+    return current.Todos, err
+  }
+
+  if err := migrate(&doc); err != nil {
+    return nil, err
+  }
+  var todos []Todo
+  err = json.Unmarshal(doc.Todos, &todos)
+  return todos, err
+}
+
+func (JSONCodec) Encode(todos []Todo) ([]byte, error) {
+  if todos == nil {
+    todos = []Todo{}
+  }
+  return json.MarshalIndent(struct {
+    Version int `json:"version"`
+    Todos []Todo `json:"todos"`
+  }{CurrentVersion, todos}, "", "  ")
+}
+
+func (c JSONCodec) Migrate(content []byte) ([]byte, bool, error) {
+  doc, err := parseDocument(content)
+  if err != nil {
+    return nil, false, err
+  }
+  if doc.Version == CurrentVersion {
+    return content, false, nil
+  }
+
+  todos, err := c.Decode(content)
+  if err != nil {
+    return nil, false, err
+  }
+  migrated, err := c.Encode(todos)
+  return migrated, err == nil, err
+}
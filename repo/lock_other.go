@@ -0,0 +1,14 @@
+//go:build !unix
+
+package repo
+
+import "os"
+
+// lockFile is a no-op on platforms without flock.
+func lockFile(f *os.File, exclusive bool) error {
+  return nil
+}
+
+func unlockFile(f *os.File) error {
+  return nil
+}
@@ -0,0 +1,340 @@
+package repo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+  markdownTask = regexp.MustCompile(`^(\s*)([-*+]) \[([ xX])\] ?(.*)$`)
+  markdownDue = regexp.MustCompile(`^due:(\d{4}-\d{2}-\d{2})$`)
+  markdownPriority = regexp.MustCompile(`^\(([A-Z])\)\s+`)
+)
+
+// MarkdownCodec reads and writes an indented markdown checklist:
+//
+//   - [ ] parent
+//     - [x] child
+//
+// The indented text under an item is its Notes. Everything else around the
+// checklist (headings, paragraphs) is remembered when decoding and written
+// back in the same place.
+// Markdown has nowhere to keep ids or expanded state, so ids are derived
+// from each item's position and name and expanded state only lives in
+// memory, as do the timestamps and History, which would clutter the
+// checklist. An (A) in front of the item text is its Priority, like in
+// todo.txt, #tags and @mentions in it become Tags, a
+// due:2026-10-20 word the Due date and a repeat:weekly_mon,thu word the
+// Repeat rule, with underscores for its spaces.
+type MarkdownCodec struct {
+  mu sync.Mutex
+  prefix []string
+  // separators[i] holds the non-task lines that follow the i-th list
+  separators [][]string
+  block map[string]int
+  after map[string][]string
+  raw map[string]markdownLine
+  ids map[string]string
+  expanded map[string]bool
+  metadata map[string]markdownMetadata
+  indent string
+  bullet string
+}
+
+type markdownLine struct {
+  text string
+  indent string
+  bullet string
+  // item is the text after the checkbox as formatMarkdownItem writes it
+  item string
+  done bool
+  depth int
+  notes string
+}
+
+// markdownMetadata is what's remembered of an item besides its expanded
+// state.
+type markdownMetadata struct {
+  createdAt string
+  completedAt string
+  updatedAt string
+  history []Event
+}
+
+type markdownNode struct {
+  todo Todo
+  indent int
+  indentText string
+  bullet string
+  block int
+  line string
+  after []string
+  children []*markdownNode
+}
+
+func NewMarkdownCodec() *MarkdownCodec {
+  return &MarkdownCodec{
+    block: map[string]int{},
+    after: map[string][]string{},
+    raw: map[string]markdownLine{},
+    ids: map[string]string{},
+    expanded: map[string]bool{},
+    metadata: map[string]markdownMetadata{},
+    indent: "  ",
+    bullet: "-",
+  }
+}
+
+func (c *MarkdownCodec) Decode(content []byte) ([]Todo, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  lines := strings.Split(string(content), "\n")
+  if len(lines) > 0 && lines[len(lines)-1] == "" {
+    lines = lines[:len(lines)-1]
+  }
+
+  c.prefix = nil
+  c.separators = nil
+  c.bullet = ""
+  var roots, stack []*markdownNode
+  var last *markdownNode
+  inSeparator := false
+  childIndent := -1
+
+  for _, line := range lines {
+    if m := markdownTask.FindStringSubmatch(line); m != nil {
+      indent := indentWidth(m[1])
+      for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+        stack = stack[:len(stack)-1]
+      }
+      todo := parseMarkdownItem(m[4])
+      todo.Done = m[3] != " "
+      node := &markdownNode{
+        todo: todo,
+        indent: indent,
+        indentText: m[1],
+        bullet: m[2],
+        block: len(c.separators),
+        line: line,
+      }
+      if len(stack) == 0 {
+        roots = append(roots, node)
+      } else {
+        parent := stack[len(stack)-1]
+        parent.children = append(parent.children, node)
+        if childIndent == -1 {
+          childIndent = indent - parent.indent
+          c.indent = strings.Repeat(" ", childIndent)
+          if strings.Contains(m[1], "\t") {
+            c.indent = "\t"
+          }
+        }
+      }
+      if c.bullet == "" {
+        c.bullet = m[2]
+      }
+      stack = append(stack, node)
+      last = node
+      inSeparator = false
+      continue
+    }
+
+    if last == nil {
+      c.prefix = append(c.prefix, line)
+      continue
+    }
+
+    continuation := line == "" || line[0] == ' ' || line[0] == '\t'
+    if continuation && !inSeparator {
+      last.after = append(last.after, line)
+      continue
+    }
+
+    if !inSeparator {
+      // blank lines ending a list belong to what follows it, so new items
+      // are added right after the last one
+      trailing := 0
+      for trailing < len(last.after) && strings.TrimSpace(last.after[len(last.after)-1-trailing]) == "" {
+        trailing++
+      }
+      separator := append([]string{}, last.after[len(last.after)-trailing:]...)
+      last.after = last.after[:len(last.after)-trailing]
+      c.separators = append(c.separators, separator)
+      stack = nil
+      inSeparator = true
+    }
+    c.separators[len(c.separators)-1] = append(c.separators[len(c.separators)-1], line)
+  }
+
+  if c.bullet == "" {
+    c.bullet = "-"
+  }
+  c.block = map[string]int{}
+  c.after = map[string][]string{}
+  c.raw = map[string]markdownLine{}
+  ids := map[string]string{}
+  todos := c.build(roots, "", 0, ids)
+  c.ids = ids
+  return todos, nil
+}
+
+func (c *MarkdownCodec) build(nodes []*markdownNode, parentId string, depth int, ids map[string]string) []Todo {
+  var todos []Todo
+  seen := map[string]int{}
+  for _, n := range nodes {
+    key := parentId + "/" + n.todo.Name + "#" + strconv.Itoa(seen[n.todo.Name])
+    seen[n.todo.Name]++
+
+    t := n.todo
+    t.Id = c.ids[key]
+    if t.Id == "" {
+      t.Id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+    }
+    ids[key] = t.Id
+
+    t.Children = c.build(n.children, t.Id, depth+1, ids)
+    t.Expanded = true
+    if expanded, ok := c.expanded[t.Id]; ok {
+      t.Expanded = expanded
+    }
+    if meta, ok := c.metadata[t.Id]; ok {
+      t.CreatedAt, t.CompletedAt, t.UpdatedAt = meta.createdAt, meta.completedAt, meta.updatedAt
+      t.History = append([]Event(nil), meta.history...)
+    }
+
+    if depth == 0 {
+      c.block[t.Id] = n.block
+    }
+    t.Notes = parseNotes(n.after)
+    c.after[t.Id] = n.after
+    c.raw[t.Id] = markdownLine{text: n.line, indent: n.indentText, bullet: n.bullet, item: formatMarkdownItem(t), done: t.Done, depth: depth, notes: t.Notes}
+    todos = append(todos, t)
+  }
+  return todos
+}
+
+func (c *MarkdownCodec) Encode(todos []Todo) ([]byte, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  blocks := make([][]Todo, len(c.separators)+1)
+  current := 0
+  for _, t := range todos {
+    if b, ok := c.block[t.Id]; ok && b > current && b < len(blocks) {
+      current = b
+    }
+    blocks[current] = append(blocks[current], t)
+  }
+
+  out := append([]string{}, c.prefix...)
+  ids := map[string]string{}
+  for i, block := range blocks {
+    for _, t := range block {
+      c.block[t.Id] = i
+    }
+    out = c.write(out, block, "", 0, ids)
+    if i < len(c.separators) {
+      out = append(out, c.separators[i]...)
+    }
+  }
+  c.ids = ids
+
+  if len(out) == 0 {
+    return []byte{}, nil
+  }
+  return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+func (c *MarkdownCodec) write(out []string, todos []Todo, parentId string, depth int, ids map[string]string) []string {
+  seen := map[string]int{}
+  indent, bullet := strings.Repeat(c.indent, depth), c.bullet
+  for _, t := range todos {
+    key := parentId + "/" + t.Name + "#" + strconv.Itoa(seen[t.Name])
+    seen[t.Name]++
+    ids[key] = t.Id
+    c.expanded[t.Id] = t.Expanded
+    c.metadata[t.Id] = markdownMetadata{
+      createdAt: t.CreatedAt,
+      completedAt: t.CompletedAt,
+      updatedAt: t.UpdatedAt,
+      history: append([]Event(nil), t.History...),
+    }
+
+    raw, ok := c.raw[t.Id]
+    if ok && raw.depth == depth {
+      indent, bullet = raw.indent, raw.bullet
+    }
+    item := formatMarkdownItem(t)
+    if !ok || raw.item != item || raw.done != t.Done || raw.depth != depth {
+      check := " "
+      if t.Done {
+        check = "x"
+      }
+      raw = markdownLine{
+        text: indent + bullet + " [" + check + "] " + item,
+        indent: indent,
+        bullet: bullet,
+        item: item,
+        done: t.Done,
+        depth: depth,
+        notes: raw.notes,
+      }
+      c.raw[t.Id] = raw
+    }
+    if raw.notes != t.Notes {
+      c.after[t.Id] = formatNotes(t.Notes, raw.indent + strings.Repeat(" ", len(raw.bullet) + 1), c.after[t.Id])
+      raw.notes = t.Notes
+      c.raw[t.Id] = raw
+    }
+
+    out = append(out, raw.text)
+    out = append(out, c.after[t.Id]...)
+    out = c.write(out, t.Children, t.Id, depth+1, ids)
+  }
+  return out
+}
+
+// parseMarkdownItem reads the text of a task after its checkbox.
+func parseMarkdownItem(text string) Todo {
+  var t Todo
+  if m := markdownPriority.FindStringSubmatch(text); m != nil {
+    t.Priority = m[1]
+    text = text[len(m[0]):]
+  }
+  var words []string
+  for _, word := range strings.Fields(text) {
+    if m := markdownDue.FindStringSubmatch(word); m != nil {
+      t.Due = m[1]
+    } else if strings.HasPrefix(word, "repeat:") && len(word) > len("repeat:") {
+      t.Repeat = strings.ReplaceAll(strings.TrimPrefix(word, "repeat:"), "_", " ")
+    } else {
+      words = append(words, word)
+    }
+  }
+  t.Name, t.Tags = SplitTags(strings.Join(words, " "))
+  return t
+}
+
+// formatMarkdownItem is the reverse of parseMarkdownItem.
+func formatMarkdownItem(t Todo) string {
+  text := JoinTags(t.Name, t.Tags)
+  if t.Priority != "" {
+    text = "(" + t.Priority + ") " + text
+  }
+  if t.Due != "" {
+    text += " due:" + t.Due
+  }
+  if t.Repeat != "" {
+    text += " repeat:" + strings.ReplaceAll(t.Repeat, " ", "_")
+  }
+  return strings.TrimLeft(text, " ")
+}
+
+func indentWidth(indent string) int {
+  return len(strings.ReplaceAll(indent, "\t", "    "))
+}
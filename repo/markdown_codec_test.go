@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reencode decodes content and encodes the result straight back.
+func reencode(t *testing.T, c Codec, content string) string {
+  t.Helper()
+  todos, err := c.Decode([]byte(content))
+  if err != nil {
+    t.Fatal(err)
+  }
+  encoded, err := c.Encode(todos)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return string(encoded)
+}
+
+// roundTrip encodes todos with one codec and decodes them with a new one, as
+// when the file is opened again later.
+func roundTrip(t *testing.T, newCodec func() Codec, todos []Todo) []Todo {
+  t.Helper()
+  content, err := newCodec().Encode(todos)
+  if err != nil {
+    t.Fatal(err)
+  }
+  decoded, err := newCodec().Decode(content)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return decoded
+}
+
+// withoutIds clears what formats without ids make up when decoding.
+func withoutIds(todos []Todo) []Todo {
+  todos = cloneTodos(todos)
+  for i := range todos {
+    todos[i].Id, todos[i].Expanded = "", false
+    todos[i].Children = withoutIds(todos[i].Children)
+  }
+  return todos
+}
+
+// richTodos uses every field the text formats know about.
+func richTodos() []Todo {
+  at := func(day, hour int) string {
+    return Timestamp(time.Date(2026, 10, day, hour, 30, 0, 0, time.Local))
+  }
+  return []Todo{
+    {
+      Id: "1",
+      Name: "plan the trip",
+      Expanded: true,
+      Priority: "A",
+      Tags: []string{"travel", "@sam"},
+      Due: "2026-10-20",
+      Repeat: "weekly mon,thu",
+      Notes: "book flights\n\nthen hotels",
+      CreatedAt: at(1, 9),
+      UpdatedAt: at(2, 10),
+      History: []Event{
+        {At: at(1, 9), Action: EventCreated},
+        {At: at(2, 10), Action: EventRenamed, From: "plan", To: "plan the trip"},
+      },
+      Children: []Todo{{
+        Id: "2",
+        Name: "pack",
+        Done: true,
+        CreatedAt: at(1, 9),
+        CompletedAt: at(3, 11),
+        UpdatedAt: at(3, 11),
+        History: []Event{{At: at(3, 11), Action: EventCompleted}},
+      }},
+    },
+    {Id: "3", Name: "water the plants"},
+  }
+}
+
+func TestMarkdownCodecKeepsText(t *testing.T) {
+  tests := []struct {
+    name string
+    content string
+  }{
+    {"empty", ""},
+    {"nested", "- [ ] a\n  - [x] b\n    - [ ] c\n- [ ] d\n"},
+    {"tabs and other bullets", "* [ ] a\n\t* [X] b\n"},
+    {"notes", "- [ ] a\n  first line\n\n  second paragraph\n- [ ] b\n"},
+    {"prose around lists", "# Trip\n\nSome intro.\n\n- [ ] a\n- [ ] b\n\n## Later\n\n- [ ] c\n\nThe end.\n"},
+    {"fields", "- [ ] (A) call #work @sam due:2026-10-20 repeat:weekly_mon,thu\n"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := reencode(t, NewMarkdownCodec(), tt.content); got != tt.content {
+        t.Errorf("re-encoded as\n%s\nwant\n%s", got, tt.content)
+      }
+    })
+  }
+}
+
+func TestMarkdownCodecDecode(t *testing.T) {
+  todos, err := NewMarkdownCodec().Decode([]byte("- [x] (B) pay rent #home @sam due:2026-11-01 repeat:monthly\n  by transfer\n"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []Todo{{
+    Name: "pay rent",
+    Done: true,
+    Priority: "B",
+    Tags: []string{"home", "@sam"},
+    Due: "2026-11-01",
+    Repeat: "monthly",
+    Notes: "by transfer",
+  }}
+  if got := withoutIds(todos); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+}
+
+// withoutMetadata clears the timestamps and History, which markdown only
+// keeps in memory.
+func withoutMetadata(todos []Todo) []Todo {
+  todos = cloneTodos(todos)
+  for i := range todos {
+    t := &todos[i]
+    t.CreatedAt, t.CompletedAt, t.UpdatedAt, t.History = "", "", "", nil
+    t.Children = withoutMetadata(t.Children)
+  }
+  return todos
+}
+
+func TestMarkdownCodecRoundTrip(t *testing.T) {
+  newCodec := func() Codec { return NewMarkdownCodec() }
+  if got, want := withoutIds(roundTrip(t, newCodec, richTodos())), withoutIds(withoutMetadata(richTodos())); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+}
+
+func TestMarkdownCodecKeepsMetadataInMemory(t *testing.T) {
+  c := NewMarkdownCodec()
+  content, err := c.Encode(richTodos())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if strings.Contains(string(content), "2026-10-01T") || strings.Contains(string(content), "renamed") {
+    t.Errorf("timestamps or history were written to the checklist:\n%s", content)
+  }
+
+  todos, err := c.Decode(content)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got, want := withoutIds(todos), withoutIds(richTodos()); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+}
+
+func TestMarkdownCodecAddsItemsInPlace(t *testing.T) {
+  c := NewMarkdownCodec()
+  todos, err := c.Decode([]byte("# Trip\n\n- [ ] a\n  notes\n- [ ] b\n\nThe end.\n"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  todos = append(todos[:1], append([]Todo{{Id: "new", Name: "new"}}, todos[1:]...)...)
+  todos[2].Done = true
+
+  content, err := c.Encode(todos)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := "# Trip\n\n- [ ] a\n  notes\n- [ ] new\n- [x] b\n\nThe end.\n"
+  if string(content) != want {
+    t.Errorf("encoded\n%s\nwant\n%s", content, want)
+  }
+}
@@ -0,0 +1,53 @@
+package repo
+
+// MemoryStore keeps todos in memory only. It is useful for tests and for
+// lists that shouldn't outlive the process.
+type MemoryStore struct {
+  todos []Todo
+}
+
+func NewMemoryStore(todos ...Todo) *MemoryStore {
+  return &MemoryStore{todos: cloneTodos(todos)}
+}
+
+func (m *MemoryStore) Load() ([]Todo, error) {
+  return cloneTodos(m.todos), nil
+}
+
+func (m *MemoryStore) Save(todos []Todo) error {
+  m.todos = cloneTodos(todos)
+  return nil
+}
+
+func (m *MemoryStore) Watch() <-chan struct{} {
+  return nil
+}
+
+// Clone deep copies todos.
+func Clone(todos []Todo) []Todo {
+  return cloneTodos(todos)
+}
+
+func cloneTodos(todos []Todo) []Todo {
+  if todos == nil {
+    return nil
+  }
+  clone := make([]Todo, len(todos))
+  for i, t := range todos {
+    clone[i] = t
+    clone[i].Children = cloneTodos(t.Children)
+    if t.Tags != nil {
+      clone[i].Tags = append([]string{}, t.Tags...)
+    }
+    if t.History != nil {
+      clone[i].History = append([]Event{}, t.History...)
+    }
+    if t.Meta != nil {
+      clone[i].Meta = make(map[string]string, len(t.Meta))
+      for k, v := range t.Meta {
+        clone[i].Meta[k] = v
+      }
+    }
+  }
+  return clone
+}
@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+type position struct {
+  parentId string
+  prevId string
+  item Todo
+}
+
+// Merge applies the changes made between base and local on top of remote,
+// which is what someone else saved in the meantime. Items are matched by Id;
+// when both sides changed the same field of an item the local value wins.
+func Merge(base, local, remote []Todo) []Todo {
+  basePositions := positions(base)
+  localPositions := positions(local)
+  result := cloneTodos(remote)
+
+  for id := range basePositions {
+    if _, ok := localPositions[id]; !ok {
+      detach(&result, id)
+    }
+  }
+
+  walk(local, "", func(t Todo, parentId, prevId string) {
+    b, inBase := basePositions[t.Id]
+    if inBase && b.parentId == parentId && b.prevId == prevId {
+      return
+    }
+
+    node, ok := detach(&result, t.Id)
+    if !ok {
+      node = t
+      node.Children = nil
+    }
+    insert(&result, parentId, prevId, node)
+  })
+
+  for id, l := range localPositions {
+    b, ok := basePositions[id]
+    if !ok {
+      continue
+    }
+    if r := find(result, id); r != nil {
+      *r = mergeFields(b.item, l.item, *r)
+    }
+  }
+
+  return result
+}
+
+func positions(todos []Todo) map[string]position {
+  p := map[string]position{}
+  walk(todos, "", func(t Todo, parentId, prevId string) {
+    p[t.Id] = position{parentId: parentId, prevId: prevId, item: t}
+  })
+  return p
+}
+
+// walk visits every item, parents before their children.
+func walk(todos []Todo, parentId string, fn func(t Todo, parentId, prevId string)) {
+  prevId := ""
+  for _, t := range todos {
+    fn(t, parentId, prevId)
+    walk(t.Children, t.Id, fn)
+    prevId = t.Id
+  }
+}
+
+func find(todos []Todo, id string) *Todo {
+  for i := range todos {
+    if todos[i].Id == id {
+      return &todos[i]
+    }
+    if found := find(todos[i].Children, id); found != nil {
+      return found
+    }
+  }
+  return nil
+}
+
+func detach(todos *[]Todo, id string) (Todo, bool) {
+  for i, t := range *todos {
+    if t.Id == id {
+      *todos = append((*todos)[:i:i], (*todos)[i+1:]...)
+      return t, true
+    }
+    if found, ok := detach(&(*todos)[i].Children, id); ok {
+      return found, true
+    }
+  }
+  return Todo{}, false
+}
+
+// insert puts t after prevId in the children of parentId. A missing parent
+// means the root list and a missing previous sibling means the end.
+func insert(todos *[]Todo, parentId, prevId string, t Todo) {
+  siblings := todos
+  if parentId != "" {
+    if parent := find(*todos, parentId); parent != nil {
+      siblings = &parent.Children
+    }
+  }
+
+  index := len(*siblings)
+  if prevId == "" {
+    index = 0
+  }
+  for i, s := range *siblings {
+    if s.Id == prevId {
+      index = i + 1
+    }
+  }
+
+  *siblings = append((*siblings)[:index:index], append([]Todo{t}, (*siblings)[index:]...)...)
+}
+
+func mergeFields(base, local, remote Todo) Todo {
+  b, l, r := fields(base), fields(local), fields(remote)
+  for k, v := range l {
+    if !bytes.Equal(v, b[k]) {
+      r[k] = v
+    }
+  }
+  for k := range b {
+    if _, ok := l[k]; !ok {
+      delete(r, k)
+    }
+  }
+
+  content, _ := json.Marshal(r)
+  var merged Todo
+  json.Unmarshal(content, &merged)
+  merged.Id = remote.Id
+  merged.Children = remote.Children
+  return merged
+}
+
+// Equal reports whether a and b hold the same todos. Like the stores, it
+// doesn't tell an empty list from a missing one.
+func Equal(a, b []Todo) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for i := range a {
+    if !reflect.DeepEqual(fields(a[i]), fields(b[i])) || !Equal(a[i].Children, b[i].Children) {
+      return false
+    }
+  }
+  return true
+}
+
+// fields returns the JSON encoded value of every field of t but Children.
+func fields(t Todo) map[string]json.RawMessage {
+  t.Children = nil
+  content, _ := json.Marshal(t)
+  f := map[string]json.RawMessage{}
+  json.Unmarshal(content, &f)
+  delete(f, "Children")
+  return f
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+// todo is a todo named after its id.
+func todo(id string, children ...Todo) Todo {
+  return Todo{Id: id, Name: id, Children: children}
+}
+
+// outline describes todos in one line like "a(b x:c) d", where c is done.
+func outline(todos []Todo) string {
+  var parts []string
+  for _, t := range todos {
+    part := t.Name
+    if t.Done {
+      part = "x:" + part
+    }
+    if len(t.Children) > 0 {
+      part += "(" + outline(t.Children) + ")"
+    }
+    parts = append(parts, part)
+  }
+  return strings.Join(parts, " ")
+}
+
+func TestMerge(t *testing.T) {
+  base := []Todo{todo("a", todo("b")), todo("c")}
+
+  tests := []struct {
+    name string
+    local []Todo
+    remote []Todo
+    want string
+  }{
+    {
+      name: "nothing changed",
+      local: base,
+      remote: base,
+      want: "a(b) c",
+    },
+    {
+      name: "remote added an item",
+      local: base,
+      remote: []Todo{todo("a", todo("b")), todo("c"), todo("d")},
+      want: "a(b) c d",
+    },
+    {
+      name: "both added items",
+      local: []Todo{todo("a", todo("b")), todo("d"), todo("c")},
+      remote: []Todo{todo("a", todo("b")), todo("c"), todo("e")},
+      want: "a(b) d c e",
+    },
+    {
+      name: "local deleted an item remote changed",
+      local: []Todo{todo("a"), todo("c")},
+      remote: []Todo{todo("a", Todo{Id: "b", Name: "B"}), todo("c")},
+      want: "a c",
+    },
+    {
+      name: "remote deleted an item",
+      local: base,
+      remote: []Todo{todo("a", todo("b"))},
+      want: "a(b)",
+    },
+    {
+      name: "local moved an item",
+      local: []Todo{todo("a", todo("b"), todo("c"))},
+      remote: []Todo{todo("a", todo("b")), todo("c"), todo("d")},
+      want: "a(b c) d",
+    },
+    {
+      name: "local moved an item remote added a child to",
+      local: []Todo{todo("c", todo("a", todo("b")))},
+      remote: []Todo{todo("a", todo("b"), todo("d")), todo("c")},
+      want: "c(a(b d))",
+    },
+    {
+      name: "both changed different fields",
+      local: []Todo{{Id: "a", Name: "A", Children: []Todo{todo("b")}}, todo("c")},
+      remote: []Todo{{Id: "a", Name: "a", Done: true, Children: []Todo{todo("b")}}, todo("c")},
+      want: "x:A(b) c",
+    },
+    {
+      name: "both changed the same field",
+      local: []Todo{todo("a", Todo{Id: "b", Name: "local"}), todo("c")},
+      remote: []Todo{todo("a", Todo{Id: "b", Name: "remote"}), todo("c")},
+      want: "a(local) c",
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := outline(Merge(base, tt.local, tt.remote)); got != tt.want {
+        t.Errorf("merged todos are %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestMergeLeavesInputsAlone(t *testing.T) {
+  base := []Todo{todo("a")}
+  local := []Todo{{Id: "a", Name: "A"}}
+  remote := []Todo{todo("a"), todo("b")}
+
+  Merge(base, local, remote)
+  if got := outline(remote); got != "a b" {
+    t.Errorf("remote became %q", got)
+  }
+}
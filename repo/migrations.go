@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the version of the JSON document written by this build.
+const CurrentVersion = 1
+
+// document is the on-disk shape of a JSON todo file. Todos is kept raw so
+// migrations can reshape it freely.
+type document struct {
+  Version int `json:"version"`
+  Todos json.RawMessage `json:"todos"`
+}
+
+// migrations[n] upgrades a document from version n to version n+1.
+var migrations = []func(doc *document) error{
+  // 0: the file was a bare array of todos, which is exactly what the first
+  // versioned document holds under "todos".
+  func(doc *document) error {
+    return nil
+  },
+}
+
+func parseDocument(content []byte) (document, error) {
+  trimmed := bytes.TrimSpace(content)
+  if len(trimmed) > 0 && trimmed[0] == '[' {
+    return document{Version: 0, Todos: trimmed}, nil
+  }
+
+  var doc document
+  err := json.Unmarshal(content, &doc)
+  return doc, err
+}
+
+func migrate(doc *document) error {
+  if doc.Version > CurrentVersion {
+    return fmt.Errorf("file has version %d but this tui-do only understands up to version %d", doc.Version, CurrentVersion)
+  }
+  for doc.Version < CurrentVersion {
+    if err := migrations[doc.Version](doc); err != nil {
+      return fmt.Errorf("migrating from version %d: %w", doc.Version, err)
+    }
+    doc.Version++
+  }
+  return nil
+}
@@ -0,0 +1,54 @@
+package repo
+
+import "strings"
+
+// parseNotes turns the lines written under an item into its notes, without
+// the blank lines around them and the indentation they share.
+func parseNotes(lines []string) string {
+  lines = lines[:len(lines)-trailingBlank(lines)]
+  for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+    lines = lines[1:]
+  }
+
+  indent := -1
+  for _, line := range lines {
+    if strings.TrimSpace(line) == "" {
+      continue
+    }
+    width := len(line) - len(strings.TrimLeft(line, " \t"))
+    if indent == -1 || width < indent {
+      indent = width
+    }
+  }
+
+  notes := make([]string, len(lines))
+  for i, line := range lines {
+    if len(line) >= indent {
+      notes[i] = strings.TrimRight(line[indent:], " \t")
+    }
+  }
+  return strings.Join(notes, "\n")
+}
+
+// formatNotes is the reverse of parseNotes. The blank lines that ended old
+// are kept so the spacing before whatever follows doesn't change.
+func formatNotes(notes string, indent string, old []string) []string {
+  var lines []string
+  if notes != "" {
+    for _, line := range strings.Split(notes, "\n") {
+      if line != "" {
+        line = indent + line
+      }
+      lines = append(lines, line)
+    }
+  }
+  return append(lines, old[len(old)-trailingBlank(old):]...)
+}
+
+func trailingBlank(lines []string) int {
+  n := 0
+  for n < len(lines) && strings.TrimSpace(lines[len(lines)-1-n]) == "" {
+    n++
+  }
+  return n
+}
@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats lists the storage formats OpenStore understands.
+var Formats = []string{"json", "sqlite", "markdown", "todotxt", "org"}
+
+// FormatOf picks the storage format for filename from its extension.
+func FormatOf(filename string) string {
+  switch strings.ToLower(filepath.Ext(filename)) {
+  case ".db", ".sqlite", ".sqlite3":
+    return "sqlite"
+  case ".md", ".markdown":
+    return "markdown"
+  case ".txt":
+    return "todotxt"
+  case ".org":
+    return "org"
+  }
+  return "json"
+}
+
+// OpenStore creates the store for filename. An empty format is derived from
+// the file's extension.
+func OpenStore(filename string, format string) (Store, error) {
+  if format == "" {
+    format = FormatOf(filename)
+  }
+
+  if format == "sqlite" {
+    return NewSQLiteStore(filename)
+  }
+  codec, err := CodecFor(format)
+  if err != nil {
+    return nil, err
+  }
+  return NewFileStore(filename, codec), nil
+}
+
+// CodecFor returns a new codec for one of the file based formats.
+func CodecFor(format string) (Codec, error) {
+  switch format {
+  case "json":
+    return JSONCodec{}, nil
+  case "markdown":
+    return NewMarkdownCodec(), nil
+  case "todotxt":
+    return NewTodoTxtCodec(), nil
+  case "org":
+    return NewOrgCodec(), nil
+  }
+  return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Open opens the todos in filename stored in the given format.
+func Open(filename string, format string) (*Repo, error) {
+  store, err := OpenStore(filename, format)
+  if err != nil {
+    return nil, err
+  }
+  r, err := New(store)
+  if err != nil {
+    if closer, ok := store.(io.Closer); ok {
+      closer.Close()
+    }
+    return nil, err
+  }
+  return r, nil
+}
+
+// OpenExisting is Open for commands that only read the todos: rather than
+// creating a missing file it returns an error.
+func OpenExisting(filename string, format string) (*Repo, error) {
+  if _, err := os.Stat(filename); err != nil {
+    return nil, err
+  }
+  return Open(filename, format)
+}
+
+// listExtensions are the extensions of the files that make up a workspace.
+var listExtensions = map[string]bool{
+  ".json": true,
+  ".db": true,
+  ".sqlite": true,
+  ".sqlite3": true,
+  ".md": true,
+  ".markdown": true,
+  ".txt": true,
+  ".org": true,
+}
+
+// ListFiles finds the todo files of a workspace directory, sorted by name.
+// Hidden files are left out.
+func ListFiles(dir string) ([]string, error) {
+  entries, err := os.ReadDir(dir)
+  if err != nil {
+    return nil, err
+  }
+  var files []string
+  for _, e := range entries {
+    name := e.Name()
+    if e.IsDir() || strings.HasPrefix(name, ".") || !listExtensions[strings.ToLower(filepath.Ext(name))] {
+      continue
+    }
+    files = append(files, filepath.Join(dir, name))
+  }
+  return files, nil
+}
+
+// ListName is the name of the list stored in filename, which is its base
+// name without the extension.
+func ListName(filename string) string {
+  base := filepath.Base(filename)
+  return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenExisting(t *testing.T) {
+  for _, name := range []string{"todos.json", "todos.db", "todos.md"} {
+    filename := filepath.Join(t.TempDir(), name)
+    if _, err := OpenExisting(filename, ""); !errors.Is(err, fs.ErrNotExist) {
+      t.Errorf("opening a missing %s returned %v", name, err)
+    }
+    if _, err := os.Stat(filename); !errors.Is(err, fs.ErrNotExist) {
+      t.Errorf("opening a missing %s created it", name)
+    }
+
+    r, err := Open(filename, "")
+    if err != nil {
+      t.Fatal(err)
+    }
+    r.Close()
+    r, err = OpenExisting(filename, "")
+    if err != nil {
+      t.Errorf("opening an existing %s returned %v", name, err)
+    } else {
+      r.Close()
+    }
+  }
+}
@@ -0,0 +1,558 @@
+package repo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+  orgHeading = regexp.MustCompile(`^(\*+)\s+(?:(TODO|DONE)\s+)?(\[#[A-Za-z0-9]\]\s+)?(.*?)(\s+:[\w@#%:]+:)?\s*$`)
+  orgProperty = regexp.MustCompile(`^\s*:([\w-]+):\s*(.*?)\s*$`)
+  orgPlanning = regexp.MustCompile(`^\s*(SCHEDULED|DEADLINE|CLOSED):`)
+  orgPlan = regexp.MustCompile(`(SCHEDULED|DEADLINE|CLOSED):\s*([<\[][^>\]]*[>\]])`)
+  orgClock = regexp.MustCompile(`\s(\d{1,2}:\d{2})`)
+  orgEvent = regexp.MustCompile(`^\s*- (\w+)(?: from ("(?:[^"\\]|\\.)*") to ("(?:[^"\\]|\\.)*"))? (\[[^\]]*\])\s*$`)
+)
+
+// OrgCodec reads and writes org-mode files. Headings are items and their
+// level gives the hierarchy, the TODO/DONE keyword gives Done, the [#A]
+// cookie the Priority, the :tags: the Tags, the DEADLINE the Due date, the
+// CLOSED date CompletedAt, the ID property the Id, the REPEAT property the
+// Repeat rule, the CREATED and UPDATED properties the other timestamps, the
+// VISIBILITY property the expanded state, the LOGBOOK drawer the History
+// and the text under the heading the Notes. Org timestamps stop at the
+// minute, so the seconds of the timestamps are lost.
+//
+// Everything else, from SCHEDULED dates to other properties, is kept as it
+// was read and written back byte for byte. Headings tui-do didn't change
+// are written back verbatim too.
+type OrgCodec struct {
+  mu sync.Mutex
+  preamble []string
+  nodes map[string]orgNode
+  ids map[string]string
+}
+
+// orgNode is a heading as it was last read or written.
+type orgNode struct {
+  heading string
+  level int
+  parentId string
+  keyword string
+  cookie string
+  name string
+  tags string
+  body []string
+  expanded bool
+  notes string
+}
+
+func NewOrgCodec() *OrgCodec {
+  return &OrgCodec{
+    nodes: map[string]orgNode{},
+    ids: map[string]string{},
+  }
+}
+
+func (c *OrgCodec) Decode(content []byte) ([]Todo, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  lines := strings.Split(string(content), "\n")
+  if len(lines) > 0 && lines[len(lines)-1] == "" {
+    lines = lines[:len(lines)-1]
+  }
+
+  type parsed struct {
+    node orgNode
+    todo Todo
+    children []*parsed
+  }
+  var roots, stack []*parsed
+  var current *parsed
+  c.preamble = nil
+
+  for _, line := range lines {
+    m := orgHeading.FindStringSubmatch(line)
+    if m == nil {
+      if current == nil {
+        c.preamble = append(c.preamble, line)
+      } else {
+        current.node.body = append(current.node.body, line)
+      }
+      continue
+    }
+
+    p := &parsed{node: orgNode{
+      heading: line,
+      level: len(m[1]),
+      keyword: m[2],
+      cookie: m[3],
+      name: m[4],
+      tags: m[5],
+    }}
+    p.todo = Todo{Name: m[4], Done: m[2] == "DONE", Priority: orgPriority(m[3]), Tags: orgTags(m[5])}
+
+    for len(stack) > 0 && stack[len(stack)-1].node.level >= p.node.level {
+      stack = stack[:len(stack)-1]
+    }
+    if len(stack) == 0 {
+      roots = append(roots, p)
+    } else {
+      parent := stack[len(stack)-1]
+      parent.children = append(parent.children, p)
+    }
+    stack = append(stack, p)
+    current = p
+  }
+
+  nodes := map[string]orgNode{}
+  ids := map[string]string{}
+  var build func(ps []*parsed, parentId string) []Todo
+  build = func(ps []*parsed, parentId string) []Todo {
+    var todos []Todo
+    seen := map[string]int{}
+    for _, p := range ps {
+      props := orgProperties(p.node.body)
+      t := p.todo
+      t.Id = props["ID"]
+      key := parentId + "/" + t.Name + "#" + strconv.Itoa(seen[t.Name])
+      seen[t.Name]++
+      if t.Id == "" {
+        t.Id = c.ids[key]
+        if t.Id == "" {
+          t.Id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+        }
+        ids[key] = t.Id
+      }
+      visibility := props["VISIBILITY"]
+      t.Expanded = visibility != "" && visibility != "folded"
+      plan := orgPlanningOf(p.node.body)
+      t.Due = orgDate(plan["DEADLINE"])
+      t.CompletedAt = orgTime(plan["CLOSED"])
+      t.Repeat = props["REPEAT"]
+      t.CreatedAt = orgTime(props["CREATED"])
+      t.UpdatedAt = orgTime(props["UPDATED"])
+      t.History = orgHistory(p.node.body)
+      t.Notes = parseNotes(p.node.body[orgNotesStart(p.node.body):])
+      p.node.notes = t.Notes
+
+      p.node.parentId = parentId
+      p.node.expanded = t.Expanded
+      nodes[t.Id] = p.node
+      t.Children = build(p.children, t.Id)
+      todos = append(todos, t)
+    }
+    return todos
+  }
+  todos := build(roots, "")
+  c.nodes = nodes
+  c.ids = ids
+  return todos, nil
+}
+
+func (c *OrgCodec) Encode(todos []Todo) ([]byte, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  out := append([]string{}, c.preamble...)
+  nodes := map[string]orgNode{}
+  ids := map[string]string{}
+  var write func(todos []Todo, parentId string, parentLevel int)
+  write = func(todos []Todo, parentId string, parentLevel int) {
+    seen := map[string]int{}
+    for _, t := range todos {
+      key := parentId + "/" + t.Name + "#" + strconv.Itoa(seen[t.Name])
+      seen[t.Name]++
+
+      node, known := c.nodes[t.Id]
+      if !known {
+        node = orgNode{body: []string{":PROPERTIES:", ":ID:       " + t.Id, ":END:"}}
+        if t.Expanded {
+          node.body = setOrgProperty(node.body, "VISIBILITY", "children")
+        }
+        node.expanded = t.Expanded
+      } else if _, hasId := orgProperties(node.body)["ID"]; !hasId {
+        ids[key] = t.Id
+      }
+
+      level := parentLevel + 1
+      if known && node.parentId == parentId && node.level > parentLevel {
+        level = node.level
+      }
+
+      done := node.keyword == "DONE"
+      if !known || node.level != level || node.name != t.Name || done != t.Done || orgPriority(node.cookie) != t.Priority || !sameTags(orgTags(node.tags), t.Tags) {
+        switch {
+        case t.Done:
+          node.keyword = "DONE"
+        case done || !known || node.keyword != "":
+          node.keyword = "TODO"
+        }
+        if orgPriority(node.cookie) != t.Priority {
+          node.cookie = ""
+          if t.Priority != "" {
+            node.cookie = "[#" + t.Priority + "] "
+          }
+        }
+        if !sameTags(orgTags(node.tags), t.Tags) {
+          node.tags = ""
+          if len(t.Tags) > 0 {
+            node.tags = " :" + strings.Join(t.Tags, ":") + ":"
+          }
+        }
+        node.level = level
+        node.name = t.Name
+        node.heading = formatOrgHeading(node)
+      }
+
+      plan := orgPlanningOf(node.body)
+      if orgDate(plan["DEADLINE"]) != t.Due {
+        node.body = setOrgPlanning(node.body, "DEADLINE", formatOrgDate(t.Due, "<", ">"))
+      }
+      if closed := formatOrgTime(t.CompletedAt); plan["CLOSED"] != closed {
+        node.body = setOrgPlanning(node.body, "CLOSED", closed)
+      }
+      node.body = updateOrgProperty(node.body, "REPEAT", t.Repeat)
+      node.body = updateOrgProperty(node.body, "CREATED", formatOrgTime(t.CreatedAt))
+      node.body = updateOrgProperty(node.body, "UPDATED", formatOrgTime(t.UpdatedAt))
+      node.body = setOrgHistory(node.body, t.History)
+
+      if node.notes != t.Notes {
+        start := orgNotesStart(node.body)
+        notes := formatNotes(t.Notes, "", node.body[start:])
+        node.body = append(node.body[:start:start], notes...)
+        node.notes = t.Notes
+      }
+
+      if node.expanded != t.Expanded {
+        visibility := "folded"
+        if t.Expanded {
+          visibility = "children"
+        }
+        node.body = setOrgProperty(node.body, "VISIBILITY", visibility)
+        node.expanded = t.Expanded
+      }
+
+      node.parentId = parentId
+      nodes[t.Id] = node
+      out = append(out, node.heading)
+      out = append(out, node.body...)
+      write(t.Children, t.Id, level)
+    }
+  }
+  write(todos, "", 0)
+  c.nodes = nodes
+  c.ids = ids
+
+  if len(out) == 0 {
+    return []byte{}, nil
+  }
+  return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// orgPriority returns the priority of a cookie like "[#A] ". Numeric
+// cookies aren't understood and are left alone.
+func orgPriority(cookie string) string {
+  if len(cookie) < 4 || cookie[2] < 'A' || cookie[2] > 'Z' {
+    return ""
+  }
+  return cookie[2:3]
+}
+
+// orgTags splits a heading's tags like " :work:@alice:".
+func orgTags(tags string) []string {
+  tags = strings.Trim(strings.TrimSpace(tags), ":")
+  if tags == "" {
+    return nil
+  }
+  return strings.Split(tags, ":")
+}
+
+func formatOrgHeading(node orgNode) string {
+  heading := strings.Repeat("*", node.level) + " "
+  if node.keyword != "" {
+    heading += node.keyword + " "
+  }
+  return heading + node.cookie + node.name + node.tags
+}
+
+// orgPlanningOf returns the timestamps on a heading's planning line, like
+// "<2026-10-20 Tue>", by keyword.
+func orgPlanningOf(body []string) map[string]string {
+  plan := map[string]string{}
+  if len(body) > 0 && orgPlanning.MatchString(body[0]) {
+    for _, m := range orgPlan.FindAllStringSubmatch(body[0], -1) {
+      plan[m[1]] = m[2]
+    }
+  }
+  return plan
+}
+
+// setOrgPlanning returns body with the keyword's timestamp on the planning
+// line replaced, or removed if timestamp is "". The other entries on the
+// line are kept.
+func setOrgPlanning(body []string, keyword, timestamp string) []string {
+  entry := ""
+  if timestamp != "" {
+    entry = keyword + ": " + timestamp
+  }
+  if len(body) == 0 || !orgPlanning.MatchString(body[0]) {
+    if entry == "" {
+      return body
+    }
+    return append([]string{entry}, body...)
+  }
+
+  line := body[0]
+  indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+  found := false
+  for _, loc := range orgPlan.FindAllStringSubmatchIndex(line, -1) {
+    if line[loc[2]:loc[3]] == keyword {
+      line = line[:loc[0]] + entry + line[loc[1]:]
+      found = true
+      break
+    }
+  }
+  switch {
+  case found || entry == "":
+  case keyword == "CLOSED":
+    // org puts CLOSED first
+    line = indent + entry + " " + line[len(indent):]
+  default:
+    line += " " + entry
+  }
+
+  updated := append([]string{}, body...)
+  if strings.TrimSpace(line) == "" {
+    return updated[1:]
+  }
+  updated[0] = indent + strings.Join(strings.Fields(line), " ")
+  return updated
+}
+
+// orgDate is the day of an org timestamp like "<2026-10-20 Tue +1w>" in
+// DateFormat, or "" if it doesn't have one.
+func orgDate(timestamp string) string {
+  if len(timestamp) < 11 {
+    return ""
+  }
+  if _, err := time.Parse(DateFormat, timestamp[1:11]); err != nil {
+    return ""
+  }
+  return timestamp[1:11]
+}
+
+// orgTime reads an org timestamp like "[2026-10-18 Sun 10:00]" in
+// TimeFormat, or in DateFormat if it has no time.
+func orgTime(timestamp string) string {
+  date := orgDate(timestamp)
+  m := orgClock.FindStringSubmatch(timestamp)
+  if date == "" || m == nil {
+    return date
+  }
+  t, err := time.ParseInLocation(DateFormat + " 15:04", date + " " + m[1], time.Local)
+  if err != nil {
+    return date
+  }
+  return Timestamp(t)
+}
+
+// formatOrgTime is the reverse of orgTime, giving an inactive timestamp.
+func formatOrgTime(timestamp string) string {
+  if len(timestamp) == len(DateFormat) {
+    return formatOrgDate(timestamp, "[", "]")
+  }
+  t, ok := ParseTimestamp(timestamp)
+  if !ok {
+    return ""
+  }
+  return "[" + t.Local().Format("2006-01-02 Mon 15:04") + "]"
+}
+
+// formatOrgDate writes a DateFormat day as an org timestamp between open
+// and close, "<" and ">" for an active one or "[" and "]" for an inactive
+// one.
+func formatOrgDate(date, open, close string) string {
+  day, err := time.Parse(DateFormat, date)
+  if err != nil {
+    return ""
+  }
+  return open + date + " " + day.Format("Mon") + close
+}
+
+// orgDrawer finds the property drawer of a heading's body. It has to come
+// first, after an optional planning line. end is -1 if there is none.
+func orgDrawer(body []string) (start, end int) {
+  start = 0
+  if len(body) > 0 && orgPlanning.MatchString(body[0]) {
+    start = 1
+  }
+  if start >= len(body) || strings.TrimSpace(body[start]) != ":PROPERTIES:" {
+    return start, -1
+  }
+  for i := start + 1; i < len(body); i++ {
+    if strings.TrimSpace(body[i]) == ":END:" {
+      return start, i
+    }
+  }
+  return start, -1
+}
+
+// orgLogbook finds the LOGBOOK drawer of a heading's body, right after its
+// property drawer. end is -1 if there is none and start where it would go.
+func orgLogbook(body []string) (start, end int) {
+  start, end = orgDrawer(body)
+  if end != -1 {
+    start = end + 1
+  }
+  if start >= len(body) || strings.TrimSpace(body[start]) != ":LOGBOOK:" {
+    return start, -1
+  }
+  for i := start + 1; i < len(body); i++ {
+    if strings.TrimSpace(body[i]) == ":END:" {
+      return start, i
+    }
+  }
+  return start, -1
+}
+
+// orgNotesStart is where the text under a heading starts, after its
+// planning line and drawers.
+func orgNotesStart(body []string) int {
+  start, end := orgLogbook(body)
+  if end == -1 {
+    return start
+  }
+  return end + 1
+}
+
+// orgHistory reads the events in a heading's LOGBOOK drawer, written like
+// `- renamed from "a" to "b" [2026-10-18 Sun 10:00]` newest first.
+func orgHistory(body []string) []Event {
+  var history []Event
+  start, end := orgLogbook(body)
+  for i := end - 1; i > start; i-- {
+    m := orgEvent.FindStringSubmatch(body[i])
+    if m == nil {
+      continue
+    }
+    e := Event{At: orgTime(m[4]), Action: m[1]}
+    if m[2] != "" {
+      e.From, _ = strconv.Unquote(m[2])
+      e.To, _ = strconv.Unquote(m[3])
+    }
+    history = append(history, e)
+  }
+  return history
+}
+
+// setOrgHistory returns body with the events in its LOGBOOK drawer
+// replaced. Other lines in the drawer, like org-mode's own clock entries,
+// are kept after them.
+func setOrgHistory(body []string, history []Event) []string {
+  var lines []string
+  for i := len(history) - 1; i >= 0; i-- {
+    e := history[i]
+    line := "- " + e.Action
+    if e.From != "" || e.To != "" {
+      line += " from " + strconv.Quote(e.From) + " to " + strconv.Quote(e.To)
+    }
+    lines = append(lines, line + " " + formatOrgTime(e.At))
+  }
+
+  start, end := orgLogbook(body)
+  rest := start
+  if end != -1 {
+    for _, line := range body[start+1:end] {
+      if !orgEvent.MatchString(line) {
+        lines = append(lines, line)
+      }
+    }
+    rest = end + 1
+  }
+  updated := append([]string{}, body[:start]...)
+  if len(lines) > 0 {
+    updated = append(append(append(updated, ":LOGBOOK:"), lines...), ":END:")
+  }
+  updated = append(updated, body[rest:]...)
+  if sameLines(updated, body) {
+    return body
+  }
+  return updated
+}
+
+func sameLines(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for i := range a {
+    if a[i] != b[i] {
+      return false
+    }
+  }
+  return true
+}
+
+func orgProperties(body []string) map[string]string {
+  props := map[string]string{}
+  start, end := orgDrawer(body)
+  for i := start + 1; i < end; i++ {
+    if m := orgProperty.FindStringSubmatch(body[i]); m != nil {
+      props[strings.ToUpper(m[1])] = m[2]
+    }
+  }
+  return props
+}
+
+// setOrgProperty returns body with the property set, touching no other line.
+func setOrgProperty(body []string, name, value string) []string {
+  start, end := orgDrawer(body)
+  line := ":" + name + ": " + value
+  if end == -1 {
+    drawer := []string{":PROPERTIES:", line, ":END:"}
+    return append(append(append([]string{}, body[:start]...), drawer...), body[start:]...)
+  }
+
+  indent := body[end][:len(body[end])-len(strings.TrimLeft(body[end], " \t"))]
+  updated := append([]string{}, body...)
+  for i := start + 1; i < end; i++ {
+    if m := orgProperty.FindStringSubmatch(body[i]); m != nil && strings.EqualFold(m[1], name) {
+      updated[i] = body[i][:strings.Index(body[i], ":")] + line
+      return updated
+    }
+  }
+  return append(append(updated[:end:end], indent + line), body[end:]...)
+}
+
+// updateOrgProperty sets the property to value if it's different, or
+// removes it if value is "".
+func updateOrgProperty(body []string, name, value string) []string {
+  if orgProperties(body)[name] == value {
+    return body
+  }
+  if value == "" {
+    return removeOrgProperty(body, name)
+  }
+  return setOrgProperty(body, name, value)
+}
+
+// removeOrgProperty returns body without the property, and without the
+// drawer if that was its last one.
+func removeOrgProperty(body []string, name string) []string {
+  start, end := orgDrawer(body)
+  for i := start + 1; i < end; i++ {
+    if m := orgProperty.FindStringSubmatch(body[i]); m != nil && strings.EqualFold(m[1], name) {
+      if end - start == 2 {
+        return append(append([]string{}, body[:start]...), body[end+1:]...)
+      }
+      return append(append([]string{}, body[:i]...), body[i+1:]...)
+    }
+  }
+  return body
+}
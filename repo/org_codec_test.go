@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrgCodecKeepsText(t *testing.T) {
+  tests := []struct {
+    name string
+    content string
+  }{
+    {"empty", ""},
+    {"nested", "#+TITLE: Trip\n\n* TODO a\n** DONE b\n*** c\n* d\n"},
+    {"notes", "* TODO a\nfirst line\n\nsecond paragraph\n* TODO b\n"},
+    {"priority and tags", "* TODO [#A] call :work:@sam:\n"},
+    {"planning and properties", "* TODO a\nSCHEDULED: <2026-10-19 Mon> DEADLINE: <2026-10-20 Tue>\n:PROPERTIES:\n:ID:       x1\n:CUSTOM:   value\n:END:\nnotes\n"},
+    {"closed", "* DONE a\nCLOSED: [2026-10-18 Sun 10:00]\n"},
+    {"logbook", "* TODO a\n:LOGBOOK:\n- renamed from \"x\" to \"a\" [2026-10-18 Sun 10:00]\nCLOCK: [2026-10-18 Sun 09:00]--[2026-10-18 Sun 10:00] =>  1:00\n:END:\n"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := reencode(t, NewOrgCodec(), tt.content); got != tt.content {
+        t.Errorf("re-encoded as\n%s\nwant\n%s", got, tt.content)
+      }
+    })
+  }
+}
+
+func TestOrgCodecDecode(t *testing.T) {
+  content := strings.Join([]string{
+    "* DONE [#B] pay rent :home:",
+    "CLOSED: [2026-10-18 Sun 10:00] DEADLINE: <2026-11-01 Sun>",
+    ":PROPERTIES:",
+    ":ID:       rent",
+    ":REPEAT:   monthly",
+    ":CREATED:  [2026-10-01 Thu]",
+    ":VISIBILITY: children",
+    ":END:",
+    ":LOGBOOK:",
+    "- completed [2026-10-18 Sun 10:00]",
+    "- created [2026-10-01 Thu]",
+    ":END:",
+    "by transfer",
+  }, "\n") + "\n"
+  todos, err := NewOrgCodec().Decode([]byte(content))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  closed := Timestamp(time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local))
+  want := []Todo{{
+    Id: "rent",
+    Name: "pay rent",
+    Done: true,
+    Expanded: true,
+    Priority: "B",
+    Tags: []string{"home"},
+    Due: "2026-11-01",
+    Repeat: "monthly",
+    Notes: "by transfer",
+    CreatedAt: "2026-10-01",
+    CompletedAt: closed,
+    History: []Event{
+      {At: "2026-10-01", Action: EventCreated},
+      {At: closed, Action: EventCompleted},
+    },
+  }}
+  if !reflect.DeepEqual(todos, want) {
+    t.Errorf("decoded %+v, want %+v", todos, want)
+  }
+}
+
+func TestOrgCodecRoundTrip(t *testing.T) {
+  newCodec := func() Codec { return NewOrgCodec() }
+  if got, want := roundTrip(t, newCodec, richTodos()), richTodos(); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+}
+
+func TestOrgCodecKeepsWhatItDoesntKnow(t *testing.T) {
+  c := NewOrgCodec()
+  todos, err := c.Decode([]byte("* TODO a\nSCHEDULED: <2026-10-19 Mon>\n:PROPERTIES:\n:CUSTOM:   value\n:END:\n"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  todos[0].Name = "b"
+  todos[0].Due = "2026-10-20"
+
+  content, err := c.Encode(todos)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := "* TODO b\nSCHEDULED: <2026-10-19 Mon> DEADLINE: <2026-10-20 Tue>\n:PROPERTIES:\n:CUSTOM:   value\n:END:\n"
+  if string(content) != want {
+    t.Errorf("encoded\n%s\nwant\n%s", content, want)
+  }
+}
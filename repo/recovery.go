@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// LatestBackup returns the path of the newest automatic backup of filename,
+// or "" if there is none.
+func LatestBackup(filename string) (string, error) {
+  backups, err := ListBackups(BackupDir(filename))
+  if err != nil || len(backups) == 0 {
+    return "", err
+  }
+  return backups[0].Path, nil
+}
+
+// RestoreBackup replaces the contents of store with the todos of the backup
+// at path.
+func RestoreBackup(store Store, path string) error {
+  todos, err := LoadBackup(path)
+  if err != nil {
+    return err
+  }
+  return store.Save(todos)
+}
+
+// MoveAside renames a broken todo file out of the way so a fresh one can be
+// created in its place. It returns the new name of the file.
+func MoveAside(filename string) (string, error) {
+  aside := fmt.Sprintf("%s.corrupt-%s", filename, time.Now().Format("20060102T150405"))
+  return aside, os.Rename(filename, aside)
+}
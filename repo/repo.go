@@ -1,50 +1,250 @@
 package repo
 
 import (
-	"encoding/json"
-	"log"
-	"os"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+// DateFormat is the layout of dates stored on a Todo.
+const DateFormat = "2006-01-02"
+
+// TimeFormat is the layout of timestamps stored on a Todo. Formats that only
+// keep dates store those in DateFormat instead.
+const TimeFormat = time.RFC3339
+
+// how many events each Todo keeps in its History
+const historyLimit = 100
+
+// Named priority levels. Any other letter up to "Z" is valid as well.
+const (
+  PriorityHigh = "A"
+  PriorityMedium = "B"
+  PriorityLow = "C"
+)
+
+// how often Persist retries after losing a race with another writer
+const conflictRetries = 3
+
 type Todo struct {
   Id string
   Name string
   Done bool
   Expanded bool
   Children []Todo
+  // Due is an optional due date in DateFormat
+  Due string `json:",omitempty"`
+  // Priority is "" for none or a letter from "A" (highest) to "Z"
+  Priority string `json:",omitempty"`
+  // Tags are labels like "backend" and mentions like "@alice"
+  Tags []string `json:",omitempty"`
+  // Repeat is a recurrence rule like "weekly mon,thu", see
+  // service.ParseRepeat
+  Repeat string `json:",omitempty"`
+  // Notes is free-form, multi-line markdown
+  Notes string `json:",omitempty"`
+  // CreatedAt, CompletedAt and UpdatedAt are in TimeFormat or DateFormat
+  CreatedAt string `json:",omitempty"`
+  CompletedAt string `json:",omitempty"`
+  UpdatedAt string `json:",omitempty"`
+  // History lists what happened to the item, oldest first
+  History []Event `json:",omitempty"`
+  // Meta holds key/value metadata from other formats, like todo.txt
+  // extensions, that has no field of its own.
+  Meta map[string]string `json:",omitempty"`
+}
+
+// Event is an entry in the History of a Todo.
+type Event struct {
+  At string
+  Action string
+  From string `json:",omitempty"`
+  To string `json:",omitempty"`
+}
+
+// Event actions.
+const (
+  EventCreated = "created"
+  EventRenamed = "renamed"
+  EventCompleted = "completed"
+  EventReopened = "reopened"
+  EventMoved = "moved"
+)
+
+// Timestamp formats t for the CreatedAt, CompletedAt and UpdatedAt fields.
+func Timestamp(t time.Time) string {
+  return t.Format(TimeFormat)
+}
+
+// ParseTimestamp reads a timestamp in TimeFormat or DateFormat, the latter
+// as midnight local time.
+func ParseTimestamp(s string) (time.Time, bool) {
+  if t, err := time.Parse(TimeFormat, s); err == nil {
+    return t, true
+  }
+  if t, err := time.ParseInLocation(DateFormat, s, time.Local); err == nil {
+    return t, true
+  }
+  return time.Time{}, false
+}
+
+// Record adds an event to the history of t, dropping the oldest ones past
+// the limit, and marks t as updated.
+func (t *Todo) Record(at time.Time, action, from, to string) {
+  t.History = append(t.History, Event{At: Timestamp(at), Action: action, From: from, To: to})
+  if len(t.History) > historyLimit {
+    t.History = append([]Event{}, t.History[len(t.History)-historyLimit:]...)
+  }
+  t.UpdatedAt = Timestamp(at)
 }
 
 type Repo struct {
-  filename string
+  store Store
   Todos []Todo
+  // base is the last state known to match the store
+  base []Todo
+  dirty bool
+  backups *BackupPolicy
+  lastBackup time.Time
 }
 
-func NewRepo(filename string) *Repo {
+// NewRepo opens the JSON todo file at filename, creating it if needed.
+func NewRepo(filename string) (*Repo, error) {
+  return New(NewFileStore(filename, JSONCodec{}))
+}
+
+// New creates a Repo on top of any Store.
+func New(store Store) (*Repo, error) {
+  todos, err := store.Load()
+  if err != nil {
+    return nil, err
+  }
+
   return &Repo{
-    filename: filename,
-    Todos: loadFromFile(filename),
+    store: store,
+    Todos: todos,
+    base: cloneTodos(todos),
+  }, nil
+}
+
+// Persist saves the todos. If the store was changed by someone else in the
+// meantime their changes are merged in first rather than overwritten.
+func (r *Repo) Persist() error {
+  if r.backups != nil && time.Since(r.lastBackup) >= r.backups.Interval {
+    if err := r.Snapshot(); err != nil {
+      return err
+    }
+  }
+
+  err := r.store.Save(r.Todos)
+  for i := 0; errors.Is(err, ErrConflict) && i < conflictRetries; i++ {
+    var remote []Todo
+    remote, err = r.store.Load()
+    if err != nil {
+      break
+    }
+    r.Todos = Merge(r.base, r.Todos, remote)
+    r.base = remote
+    err = r.store.Save(r.Todos)
+  }
+
+  r.dirty = err != nil
+  if err == nil {
+    r.base = cloneTodos(r.Todos)
+  }
+  return err
+}
+
+// Reload reads the todos from the store again after an outside change. When
+// there are local changes that haven't been saved yet they are merged with
+// the new contents and saved; merged reports whether that happened.
+func (r *Repo) Reload() (merged bool, err error) {
+  remote, err := r.store.Load()
+  if err != nil {
+    return false, err
+  }
+
+  if !r.dirty {
+    r.Todos = remote
+    r.base = cloneTodos(remote)
+    return false, nil
+  }
+
+  r.Todos = Merge(r.base, r.Todos, remote)
+  r.base = remote
+  return true, r.Persist()
+}
+
+// EnableBackups makes Persist snapshot the stored todos according to policy
+// before overwriting them.
+func (r *Repo) EnableBackups(policy BackupPolicy) {
+  r.backups = &policy
+  if backups, err := ListBackups(policy.Dir); err == nil && len(backups) > 0 {
+    r.lastBackup = backups[0].Time
   }
 }
 
-func loadFromFile(filename string) []Todo {
-  var payload []Todo
-  content, err := os.ReadFile(filename)
-  if os.IsNotExist(err) {
-    content = []byte("[]")
-    os.WriteFile(filename, content, 0644)
-  } else if err != nil {
-    log.Fatal("Error when opening file: ", err)
+// Snapshot writes a backup of the last saved todos right away.
+func (r *Repo) Snapshot() error {
+  if r.backups == nil {
+    return nil
   }
+  now := time.Now()
+  if err := writeBackup(*r.backups, r.base, now); err != nil {
+    return err
+  }
+  r.lastBackup = now
+  return nil
+}
+
+// Backups lists the snapshots taken of this repo, newest first.
+func (r *Repo) Backups() ([]Backup, error) {
+  if r.backups == nil {
+    return nil, nil
+  }
+  return ListBackups(r.backups.Dir)
+}
 
-  err = json.Unmarshal(content, &payload)
+// Restore replaces the todos with the ones from a snapshot. The current
+// todos are snapshotted first so the restore itself can be undone.
+func (r *Repo) Restore(b Backup) error {
+  todos, err := LoadBackup(b.Path)
   if err != nil {
-    log.Fatal("Error during Unmarshal(): ", err)
+    return err
   }
+  if err := r.Snapshot(); err != nil {
+    return err
+  }
+  r.Todos = todos
+  return r.Persist()
+}
+
+// Watch reports changes made to the underlying store by someone else.
+func (r *Repo) Watch() <-chan struct{} {
+  return r.store.Watch()
+}
 
-  return payload
+// Close releases what the store holds on to, like its watcher or database
+// connection.
+func (r *Repo) Close() error {
+  if closer, ok := r.store.(io.Closer); ok {
+    return closer.Close()
+  }
+  return nil
 }
 
-func (r *Repo) Persist() {
-  content, _ := json.MarshalIndent(r.Todos, "", "  ")
-  os.WriteFile(r.filename, content, 0644)
+// CopyWithNewIds deep copies todos, giving every item a fresh id.
+func CopyWithNewIds(todos []Todo) []Todo {
+  clone := cloneTodos(todos)
+  var renew func(todos []Todo)
+  renew = func(todos []Todo) {
+    for i := range todos {
+      todos[i].Id = uuid.New().String()
+      renew(todos[i].Children)
+    }
+  }
+  renew(clone)
+  return clone
 }
@@ -0,0 +1,397 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations[n] upgrades a database from user_version n to n+1.
+var sqliteMigrations = []string{
+  `CREATE TABLE todos (
+    id TEXT PRIMARY KEY,
+    parent_id TEXT,
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0,
+    expanded INTEGER NOT NULL DEFAULT 0,
+    data TEXT NOT NULL DEFAULT '{}'
+  );
+  CREATE INDEX todos_parent ON todos (parent_id, position);
+  CREATE TABLE revision (value INTEGER NOT NULL);
+  INSERT INTO revision (value) VALUES (0);`,
+}
+
+// positionGap is the space left between the positions of siblings, so an
+// item can go between two others without renumbering the rest.
+const positionGap = 1 << 16
+
+// row is one todo as stored in the todos table. Fields of Todo that don't
+// have their own column are kept as a JSON object in the data column.
+type row struct {
+  parentId string
+  position int64
+  // item is the todo without its children
+  item Todo
+}
+
+// SQLiteStore keeps todos in a SQLite database, one row per todo. Save only
+// writes the rows that changed since the last Load or Save, so toggling an
+// item is a single row update no matter how long the list is. Every save
+// bumps the revision table, which is how Save tells that another process
+// saved in the meantime and refuses with ErrConflict.
+type SQLiteStore struct {
+  db *sql.DB
+  synced map[string]row
+  revision int64
+
+  mu sync.Mutex
+  watch chan struct{}
+  // done stops the polling Watch started
+  done chan struct{}
+}
+
+func NewSQLiteStore(filename string) (*SQLiteStore, error) {
+  db, err := sql.Open("sqlite", filename)
+  if err != nil {
+    return nil, err
+  }
+  // a single connection keeps PRAGMA data_version meaningful for Watch
+  db.SetMaxOpenConns(1)
+  if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+    db.Close()
+    return nil, err
+  }
+
+  s := &SQLiteStore{db: db}
+  if err := s.migrate(); err != nil {
+    db.Close()
+    return nil, fmt.Errorf("%s: %w", filename, err)
+  }
+  return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+  var version int
+  if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+    return err
+  }
+  if version > len(sqliteMigrations) {
+    return fmt.Errorf("database has version %d but this tui-do only understands up to version %d", version, len(sqliteMigrations))
+  }
+
+  for ; version < len(sqliteMigrations); version++ {
+    tx, err := s.db.Begin()
+    if err != nil {
+      return err
+    }
+    if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+      tx.Rollback()
+      return fmt.Errorf("migrating from version %d: %w", version, err)
+    }
+    if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+      tx.Rollback()
+      return err
+    }
+    if err := tx.Commit(); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func (s *SQLiteStore) Load() ([]Todo, error) {
+  tx, err := s.db.Begin()
+  if err != nil {
+    return nil, err
+  }
+  defer tx.Rollback()
+
+  var revision int64
+  if err := tx.QueryRow("SELECT value FROM revision").Scan(&revision); err != nil {
+    return nil, err
+  }
+  rows, err := tx.Query("SELECT id, COALESCE(parent_id, ''), position, name, done, expanded, data FROM todos ORDER BY position")
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  synced := map[string]row{}
+  var order []string
+  for rows.Next() {
+    var id, name, data string
+    var done, expanded bool
+    var r row
+    if err := rows.Scan(&id, &r.parentId, &r.position, &name, &done, &expanded, &data); err != nil {
+      return nil, err
+    }
+    if err := json.Unmarshal([]byte(data), &r.item); err != nil {
+      return nil, fmt.Errorf("todo %s: %w", id, err)
+    }
+    r.item.Id, r.item.Name, r.item.Done, r.item.Expanded = id, name, done, expanded
+    synced[id] = r
+    order = append(order, id)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  children := map[string][]string{}
+  for _, id := range order {
+    parentId := synced[id].parentId
+    if _, ok := synced[parentId]; !ok {
+      parentId = ""
+    }
+    children[parentId] = append(children[parentId], id)
+  }
+
+  var build func(parentId string) []Todo
+  build = func(parentId string) []Todo {
+    var todos []Todo
+    for _, id := range children[parentId] {
+      t := cloneTodos([]Todo{synced[id].item})[0]
+      t.Children = build(id)
+      todos = append(todos, t)
+    }
+    return todos
+  }
+
+  todos := build("")
+  s.synced = synced
+  s.revision = revision
+  return todos, nil
+}
+
+// Save writes the rows that changed since the last Load or Save. It refuses
+// with ErrConflict if someone else saved since then, unless this store
+// never loaded the database at all.
+func (s *SQLiteStore) Save(todos []Todo) error {
+  current := map[string]row{}
+  var flatten func(todos []Todo, parentId string)
+  flatten = func(todos []Todo, parentId string) {
+    positions := s.positions(todos, parentId)
+    for i, t := range todos {
+      item := t
+      item.Children = nil
+      current[t.Id] = row{parentId: parentId, position: positions[i], item: item}
+      flatten(t.Children, t.Id)
+    }
+  }
+  flatten(todos, "")
+
+  tx, err := s.db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  bump, args := "UPDATE revision SET value = value + 1", []any{}
+  if s.synced != nil {
+    bump, args = bump + " WHERE value = ?", []any{s.revision}
+  }
+  result, err := tx.Exec(bump, args...)
+  if err != nil {
+    return err
+  }
+  if n, err := result.RowsAffected(); err != nil || n == 0 {
+    return ErrConflict
+  }
+  var revision int64
+  if err := tx.QueryRow("SELECT value FROM revision").Scan(&revision); err != nil {
+    return err
+  }
+
+  for id, r := range current {
+    old, ok := s.synced[id]
+    same := ok && sameItem(old.item, r.item)
+    switch {
+    case same && old.parentId == r.parentId && old.position == r.position:
+      r.item = old.item
+    case same:
+      // only moved, so the data column stays as it is
+      if _, err := tx.Exec("UPDATE todos SET parent_id = NULLIF(?, ''), position = ? WHERE id = ?", r.parentId, r.position, id); err != nil {
+        return err
+      }
+      r.item = old.item
+    default:
+      data := fields(r.item)
+      for _, core := range []string{"Id", "Name", "Done", "Expanded"} {
+        delete(data, core)
+      }
+      content, _ := json.Marshal(data)
+      _, err := tx.Exec(`INSERT INTO todos (id, parent_id, position, name, done, expanded, data)
+        VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?)
+        ON CONFLICT (id) DO UPDATE SET parent_id = excluded.parent_id, position = excluded.position,
+          name = excluded.name, done = excluded.done, expanded = excluded.expanded, data = excluded.data`,
+        id, r.parentId, r.position, r.item.Name, r.item.Done, r.item.Expanded, string(content))
+      if err != nil {
+        return err
+      }
+      r.item = cloneTodos([]Todo{r.item})[0]
+    }
+    current[id] = r
+  }
+  for id := range s.synced {
+    if _, ok := current[id]; !ok {
+      if _, err := tx.Exec("DELETE FROM todos WHERE id = ?", id); err != nil {
+        return err
+      }
+    }
+  }
+  if err := tx.Commit(); err != nil {
+    return err
+  }
+
+  s.synced = current
+  s.revision = revision
+  return nil
+}
+
+// positions picks the position of each of the siblings under parentId. The
+// longest run of them still in the order they were stored in keeps its
+// positions, so deleting or moving an item doesn't touch its siblings.
+func (s *SQLiteStore) positions(siblings []Todo, parentId string) []int64 {
+  stored := make([]int64, len(siblings))
+  known := make([]bool, len(siblings))
+  for i, t := range siblings {
+    if r, ok := s.synced[t.Id]; ok && r.parentId == parentId {
+      stored[i], known[i] = r.position, true
+    }
+  }
+  keep := increasingRun(stored, known)
+
+  positions := make([]int64, len(siblings))
+  for i := 0; i < len(siblings); {
+    if keep[i] {
+      positions[i] = stored[i]
+      i++
+      continue
+    }
+    j := i
+    for j < len(siblings) && !keep[j] {
+      j++
+    }
+    // spread the items from i to j between the kept ones around them
+    n := int64(j - i)
+    var lo, hi int64
+    switch {
+    case i > 0 && j < len(siblings):
+      lo, hi = positions[i-1], stored[j]
+    case i > 0:
+      lo = positions[i-1]
+      hi = lo + (n+1) * positionGap
+    case j < len(siblings):
+      hi = stored[j]
+      lo = hi - (n+1) * positionGap
+    default:
+      hi = (n+1) * positionGap
+    }
+    if hi - lo <= n {
+      // no room left, renumber them all
+      for k := range positions {
+        positions[k] = int64(k+1) * positionGap
+      }
+      return positions
+    }
+    for k := i; k < j; k++ {
+      positions[k] = lo + (hi - lo) * int64(k-i+1) / (n+1)
+    }
+    i = j
+  }
+  return positions
+}
+
+// increasingRun marks the longest run of known values, not necessarily next
+// to each other, that increase.
+func increasingRun(values []int64, known []bool) []bool {
+  // tails[k] is the index ending the best run of length k+1 so far
+  var tails []int
+  prev := make([]int, len(values))
+  for i := range values {
+    if !known[i] {
+      continue
+    }
+    k := sort.Search(len(tails), func(k int) bool { return values[tails[k]] >= values[i] })
+    prev[i] = -1
+    if k > 0 {
+      prev[i] = tails[k-1]
+    }
+    if k == len(tails) {
+      tails = append(tails, i)
+    } else {
+      tails[k] = i
+    }
+  }
+
+  keep := make([]bool, len(values))
+  if len(tails) > 0 {
+    for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+      keep[i] = true
+    }
+  }
+  return keep
+}
+
+// sameItem reports whether a and b are the same apart from their children.
+func sameItem(a, b Todo) bool {
+  a.Children, b.Children = nil, nil
+  return reflect.DeepEqual(a, b)
+}
+
+// Watch polls PRAGMA data_version, which only changes when another
+// connection commits to the database.
+func (s *SQLiteStore) Watch() <-chan struct{} {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if s.watch == nil {
+    var version int
+    s.db.QueryRow("PRAGMA data_version").Scan(&version)
+    s.watch = make(chan struct{}, 1)
+    s.done = make(chan struct{})
+    go s.poll(version, s.watch, s.done)
+  }
+  return s.watch
+}
+
+// Close stops watching the database and closes it.
+func (s *SQLiteStore) Close() error {
+  s.mu.Lock()
+  if s.done != nil {
+    close(s.done)
+    s.watch, s.done = nil, nil
+  }
+  s.mu.Unlock()
+  return s.db.Close()
+}
+
+func (s *SQLiteStore) poll(last int, watch chan struct{}, done chan struct{}) {
+  ticker := time.NewTicker(watchInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-done:
+      return
+    case <-ticker.C:
+    }
+
+    var version int
+    if err := s.db.QueryRow("PRAGMA data_version").Scan(&version); err != nil {
+      continue
+    }
+    if version != last {
+      select {
+      case watch <- struct{}{}:
+      default:
+      }
+    }
+    last = version
+  }
+}
@@ -0,0 +1,285 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func openSQLite(t *testing.T, filename string) *SQLiteStore {
+  t.Helper()
+  s, err := NewSQLiteStore(filename)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { s.Close() })
+  return s
+}
+
+// newSQLite creates a database holding todos and returns a store that has
+// loaded it.
+func newSQLite(t *testing.T, todos ...Todo) *SQLiteStore {
+  t.Helper()
+  filename := filepath.Join(t.TempDir(), "todos.db")
+  if err := openSQLite(t, filename).Save(todos); err != nil {
+    t.Fatal(err)
+  }
+  s := openSQLite(t, filename)
+  if _, err := s.Load(); err != nil {
+    t.Fatal(err)
+  }
+  return s
+}
+
+// recordWrites returns a function listing the ids of the rows inserted or
+// updated since it was called.
+func recordWrites(t *testing.T, s *SQLiteStore) func() []string {
+  t.Helper()
+  _, err := s.db.Exec(`CREATE TABLE writes (id TEXT);
+    CREATE TRIGGER todos_insert AFTER INSERT ON todos BEGIN INSERT INTO writes VALUES (new.id); END;
+    CREATE TRIGGER todos_update AFTER UPDATE ON todos BEGIN INSERT INTO writes VALUES (new.id); END;`)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return func() []string {
+    rows, err := s.db.Query("SELECT id FROM writes")
+    if err != nil {
+      t.Fatal(err)
+    }
+    defer rows.Close()
+    var ids []string
+    for rows.Next() {
+      var id string
+      rows.Scan(&id)
+      ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    return ids
+  }
+}
+
+// storedPositions maps each id to its position column.
+func storedPositions(t *testing.T, s *SQLiteStore) map[string]int64 {
+  t.Helper()
+  rows, err := s.db.Query("SELECT id, position FROM todos")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer rows.Close()
+  positions := map[string]int64{}
+  for rows.Next() {
+    var id string
+    var position int64
+    rows.Scan(&id, &position)
+    positions[id] = position
+  }
+  return positions
+}
+
+func TestSQLiteStoreSave(t *testing.T) {
+  tests := []struct {
+    name string
+    change func(todos []Todo) []Todo
+    want string
+    wantWrites []string
+  }{
+    {
+      name: "nothing changed",
+      change: func(todos []Todo) []Todo { return todos },
+      want: "a(b) c d",
+    },
+    {
+      name: "rename",
+      change: func(todos []Todo) []Todo {
+        todos[1].Name = "C"
+        return todos
+      },
+      want: "a(b) C d",
+      wantWrites: []string{"c"},
+    },
+    {
+      name: "insert between siblings",
+      change: func(todos []Todo) []Todo {
+        return append(todos[:2:2], append([]Todo{todo("x")}, todos[2:]...)...)
+      },
+      want: "a(b) c x d",
+      wantWrites: []string{"x"},
+    },
+    {
+      name: "insert first",
+      change: func(todos []Todo) []Todo { return append([]Todo{todo("x")}, todos...) },
+      want: "x a(b) c d",
+      wantWrites: []string{"x"},
+    },
+    {
+      name: "delete",
+      change: func(todos []Todo) []Todo { return append(todos[:1:1], todos[2:]...) },
+      want: "a(b) d",
+    },
+    {
+      name: "delete a parent",
+      change: func(todos []Todo) []Todo { return todos[1:] },
+      want: "c d",
+    },
+    {
+      name: "move across parents",
+      change: func(todos []Todo) []Todo {
+        todos[0].Children = append(todos[0].Children, todos[1])
+        return []Todo{todos[0], todos[2]}
+      },
+      want: "a(b c) d",
+      wantWrites: []string{"c"},
+    },
+    {
+      name: "move to the end",
+      change: func(todos []Todo) []Todo { return []Todo{todos[1], todos[2], todos[0]} },
+      want: "c d a(b)",
+      wantWrites: []string{"a"},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s := newSQLite(t, todo("a", todo("b")), todo("c"), todo("d"))
+      todos, err := s.Load()
+      if err != nil {
+        t.Fatal(err)
+      }
+      before := storedPositions(t, s)
+      writes := recordWrites(t, s)
+
+      if err := s.Save(tt.change(todos)); err != nil {
+        t.Fatal(err)
+      }
+      if got := writes(); !reflect.DeepEqual(got, tt.wantWrites) {
+        t.Errorf("wrote rows %v, want %v", got, tt.wantWrites)
+      }
+      // rows that weren't written kept their positions
+      written := map[string]bool{}
+      for _, id := range tt.wantWrites {
+        written[id] = true
+      }
+      for id, position := range storedPositions(t, s) {
+        if old, ok := before[id]; ok && old != position && !written[id] {
+          t.Errorf("%s moved from position %d to %d", id, old, position)
+        }
+      }
+
+      loaded, err := openSQLite(t, dbFile(t, s)).Load()
+      if err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(loaded); got != tt.want {
+        t.Errorf("loaded %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+// dbFile is the file a store has open.
+func dbFile(t *testing.T, s *SQLiteStore) string {
+  t.Helper()
+  var seq int
+  var name, file string
+  if err := s.db.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file); err != nil {
+    t.Fatal(err)
+  }
+  return file
+}
+
+func TestSQLiteStoreRenumbersWhenFull(t *testing.T) {
+  s := newSQLite(t, todo("a"), todo("b"))
+  if _, err := s.db.Exec("UPDATE todos SET position = CASE id WHEN 'a' THEN 1 ELSE 2 END"); err != nil {
+    t.Fatal(err)
+  }
+  todos, err := s.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if err := s.Save([]Todo{todos[0], todo("x"), todos[1]}); err != nil {
+    t.Fatal(err)
+  }
+  want := map[string]int64{"a": positionGap, "x": 2 * positionGap, "b": 3 * positionGap}
+  if got := storedPositions(t, s); !reflect.DeepEqual(got, want) {
+    t.Errorf("positions are %v, want %v", got, want)
+  }
+  loaded, err := s.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(loaded); got != "a x b" {
+    t.Errorf("loaded %q, want %q", got, "a x b")
+  }
+}
+
+func TestSQLiteStoreFillsGaps(t *testing.T) {
+  s := newSQLite(t, todo("a"), todo("b"))
+  todos, err := s.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+  // keep inserting right after a until the gap runs out
+  for i := 0; i < 40; i++ {
+    todos = append(todos[:1:1], append([]Todo{todo(fmt.Sprint(i))}, todos[1:]...)...)
+    if err := s.Save(todos); err != nil {
+      t.Fatal(err)
+    }
+  }
+  loaded, err := s.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(loaded); got != outline(todos) {
+    t.Errorf("loaded %q, want %q", got, outline(todos))
+  }
+}
+
+func TestSQLiteStoreConflict(t *testing.T) {
+  s := newSQLite(t, todo("a"))
+  other := openSQLite(t, dbFile(t, s))
+  if _, err := other.Load(); err != nil {
+    t.Fatal(err)
+  }
+
+  if err := s.Save([]Todo{todo("a"), todo("b")}); err != nil {
+    t.Fatal(err)
+  }
+  if err := other.Save([]Todo{todo("a"), todo("c")}); !errors.Is(err, ErrConflict) {
+    t.Fatalf("the second save returned %v, want ErrConflict", err)
+  }
+
+  todos, err := other.Load()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(todos); got != "a b" {
+    t.Fatalf("loaded %q after the conflict, want %q", got, "a b")
+  }
+  if err := other.Save(append(todos, todo("c"))); err != nil {
+    t.Errorf("saving after loading again returned %v", err)
+  }
+}
+
+func TestSQLiteStoreClose(t *testing.T) {
+  filename := filepath.Join(t.TempDir(), "todos.db")
+  r, err := Open(filename, "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  s := r.store.(*SQLiteStore)
+  r.Watch()
+
+  if err := r.Close(); err != nil {
+    t.Fatal(err)
+  }
+  if s.watch != nil || s.done != nil {
+    t.Error("closing didn't stop watching the database")
+  }
+  if err := s.db.Ping(); err == nil {
+    t.Error("the database is still open")
+  }
+}
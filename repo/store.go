@@ -0,0 +1,24 @@
+package repo
+
+// Store is the storage backend a Repo loads its todos from and saves them to.
+type Store interface {
+  Load() ([]Todo, error)
+  Save(todos []Todo) error
+  // Watch returns a channel that receives a value whenever the stored todos
+  // are changed by someone other than this Store. Stores that can't detect
+  // outside changes return a nil channel.
+  Watch() <-chan struct{}
+}
+
+// Codec converts todos to and from the bytes of a file based store.
+type Codec interface {
+  Decode(content []byte) ([]Todo, error)
+  Encode(todos []Todo) ([]byte, error)
+}
+
+// Migrator is implemented by codecs whose format is versioned. Migrate
+// upgrades content written by an older version to the current format and
+// reports whether anything had to change.
+type Migrator interface {
+  Migrate(content []byte) (migrated []byte, changed bool, err error)
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"regexp"
+	"strings"
+)
+
+var tagWord = regexp.MustCompile(`^([#@])([\p{L}_][\p{L}\p{N}_/-]*)$`)
+
+// SplitTags pulls the #tag and @mention words out of text. Tags are kept
+// without their "#" and mentions with their "@", so "#backend @alice"
+// gives the tags "backend" and "@alice". Words that only look a bit like a
+// tag, like "#12", stay in the name.
+func SplitTags(text string) (name string, tags []string) {
+  var words []string
+  for _, word := range strings.Fields(text) {
+    m := tagWord.FindStringSubmatch(word)
+    if m == nil {
+      words = append(words, word)
+      continue
+    }
+    tag := m[2]
+    if m[1] == "@" {
+      tag = word
+    }
+    tags = AddTag(tags, tag)
+  }
+  return strings.Join(words, " "), tags
+}
+
+// JoinTags is the reverse of SplitTags.
+func JoinTags(name string, tags []string) string {
+  for _, tag := range tags {
+    name += " " + TagLabel(tag)
+  }
+  return strings.TrimLeft(name, " ")
+}
+
+// TagLabel is how a tag is written: "#backend" or "@alice".
+func TagLabel(tag string) string {
+  if strings.HasPrefix(tag, "@") {
+    return tag
+  }
+  return "#" + tag
+}
+
+// NormalizeTag turns user input like "#Backend" into the stored form.
+func NormalizeTag(tag string) string {
+  return strings.TrimPrefix(strings.TrimSpace(tag), "#")
+}
+
+// HasTag reports whether t carries tag, ignoring case.
+func (t Todo) HasTag(tag string) bool {
+  for _, existing := range t.Tags {
+    if strings.EqualFold(existing, tag) {
+      return true
+    }
+  }
+  return false
+}
+
+// AddTag appends tag unless it's already in tags, ignoring case.
+func AddTag(tags []string, tag string) []string {
+  if (Todo{Tags: tags}).HasTag(tag) {
+    return tags
+  }
+  return append(tags, tag)
+}
+
+func sameTags(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for i := range a {
+    if a[i] != b[i] {
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,306 @@
+package repo
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+  todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\) `)
+  todoTxtDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `)
+  todoTxtExtension = regexp.MustCompile(`^([^\s:]+):([^\s:/][^\s]*)$`)
+)
+
+// TodoTxtCodec reads and writes the todo.txt format, one item per line:
+//
+//   x 2026-10-18 2026-10-01 call the plumber +house @phone due:2026-10-20
+//
+// Completion maps to Done, the (A) priority to Priority, due: to Due, the
+// dates to CompletedAt and CreatedAt, rec: to Repeat and any other
+// key:value extensions to Meta. Repeat rules rec: can't express are written
+// as a repeat: extension. +project and @context become the Tags "project"
+// and "@context". Done items keep their priority as a pri: extension.
+// Items with children get an id: extension that their children point to
+// with parent:. Other items get an id derived from their text. Words of a
+// name that would be read back as any of this get a backslash in front.
+// There is no room for multi-line Notes, so they aren't kept.
+type TodoTxtCodec struct {
+  mu sync.Mutex
+  ids map[string]string
+  expanded map[string]bool
+}
+
+func NewTodoTxtCodec() *TodoTxtCodec {
+  return &TodoTxtCodec{
+    ids: map[string]string{},
+    expanded: map[string]bool{},
+  }
+}
+
+func (c *TodoTxtCodec) Decode(content []byte) ([]Todo, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  type entry struct {
+    todo Todo
+    parentId string
+  }
+  var entries []entry
+  ids := map[string]string{}
+  seen := map[string]int{}
+
+  for _, line := range strings.Split(string(content), "\n") {
+    line = strings.TrimSpace(line)
+    if line == "" {
+      continue
+    }
+
+    t, parentId := parseTodoTxt(line)
+    if t.Id == "" {
+      key := t.Name + "#" + strconv.Itoa(seen[t.Name])
+      seen[t.Name]++
+      t.Id = c.ids[key]
+      if t.Id == "" {
+        t.Id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+      }
+      ids[key] = t.Id
+    }
+    t.Expanded = true
+    if expanded, ok := c.expanded[t.Id]; ok {
+      t.Expanded = expanded
+    }
+    entries = append(entries, entry{t, parentId})
+  }
+  c.ids = ids
+
+  known := map[string]bool{}
+  for _, e := range entries {
+    known[e.todo.Id] = true
+  }
+  children := map[string][]Todo{}
+  for _, e := range entries {
+    parentId := e.parentId
+    if !known[parentId] || parentId == e.todo.Id {
+      parentId = ""
+    }
+    children[parentId] = append(children[parentId], e.todo)
+  }
+
+  attached := map[string]bool{}
+  var attach func(todos []Todo) []Todo
+  attach = func(todos []Todo) []Todo {
+    var result []Todo
+    for _, t := range todos {
+      if attached[t.Id] {
+        continue
+      }
+      attached[t.Id] = true
+      t.Children = attach(children[t.Id])
+      result = append(result, t)
+    }
+    return result
+  }
+  todos := attach(children[""])
+
+  // items whose parent: links form a cycle are never reached from the top
+  for _, e := range entries {
+    if !attached[e.todo.Id] {
+      todos = append(todos, attach([]Todo{e.todo})...)
+    }
+  }
+  return todos, nil
+}
+
+func parseTodoTxt(line string) (Todo, string) {
+  var t Todo
+  meta := map[string]string{}
+
+  if strings.HasPrefix(line, "x ") {
+    t.Done = true
+    line = line[2:]
+    if todoTxtDate.MatchString(line) {
+      t.CompletedAt = line[:10]
+      line = line[11:]
+    }
+  }
+  if m := todoTxtPriority.FindStringSubmatch(line); m != nil {
+    t.Priority = m[1]
+    line = line[len(m[0]):]
+  }
+  if todoTxtDate.MatchString(line) {
+    t.CreatedAt = line[:10]
+    line = line[11:]
+  }
+
+  var words []string
+  parentId := ""
+  for _, word := range strings.Fields(line) {
+    if len(word) > 1 && word[0] == '\\' {
+      words = append(words, word[1:])
+      continue
+    }
+    m := todoTxtExtension.FindStringSubmatch(word)
+    switch {
+    case m == nil && len(word) > 1 && word[0] == '+':
+      t.Tags = AddTag(t.Tags, word[1:])
+    case m == nil && len(word) > 1 && word[0] == '@':
+      t.Tags = AddTag(t.Tags, word)
+    case m == nil:
+      words = append(words, word)
+    case m[1] == "id":
+      t.Id = m[2]
+    case m[1] == "parent":
+      parentId = m[2]
+    case m[1] == "due":
+      t.Due = m[2]
+    case m[1] == "created":
+      t.CreatedAt = m[2]
+    case m[1] == "rec":
+      t.Repeat = "every " + strings.TrimPrefix(m[2], "+")
+    case m[1] == "repeat":
+      t.Repeat = strings.ReplaceAll(m[2], "_", " ")
+    case m[1] == "pri" && len(m[2]) == 1:
+      t.Priority = strings.ToUpper(m[2])
+    default:
+      meta[m[1]] = m[2]
+    }
+  }
+
+  t.Name = strings.Join(words, " ")
+  if len(meta) > 0 {
+    t.Meta = meta
+  }
+  return t, parentId
+}
+
+func (c *TodoTxtCodec) Encode(todos []Todo) ([]byte, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  var lines []string
+  ids := map[string]string{}
+  seen := map[string]int{}
+  var write func(todos []Todo, parentId string)
+  write = func(todos []Todo, parentId string) {
+    for _, t := range todos {
+      c.expanded[t.Id] = t.Expanded
+      withId := len(t.Children) > 0
+      if !withId {
+        key := t.Name + "#" + strconv.Itoa(seen[t.Name])
+        seen[t.Name]++
+        ids[key] = t.Id
+      }
+      lines = append(lines, formatTodoTxt(t, parentId, withId))
+      write(t.Children, t.Id)
+    }
+  }
+  write(todos, "")
+  c.ids = ids
+
+  if len(lines) == 0 {
+    return []byte{}, nil
+  }
+  return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// todoTxtRec has the rec: intervals of the Repeat rules without one.
+var todoTxtRec = map[string]string{
+  "daily": "1d",
+  "weekly": "1w",
+  "monthly": "1m",
+}
+
+// todoTxtDay is the local date of a timestamp, or "" if there is none.
+func todoTxtDay(timestamp string) string {
+  t, ok := ParseTimestamp(timestamp)
+  if !ok {
+    return ""
+  }
+  return t.Local().Format(DateFormat)
+}
+
+// escapeTodoTxt puts a backslash in front of a word of a name that would
+// be read back as something else, like a +project or a key:value
+// extension, or as the completion mark, priority or date when it's first.
+func escapeTodoTxt(word string, first bool) string {
+  special := word[0] == '\\' || todoTxtExtension.MatchString(word) ||
+    (len(word) > 1 && (word[0] == '+' || word[0] == '@'))
+  if first {
+    special = special || word == "x" || todoTxtPriority.MatchString(word + " ") || todoTxtDate.MatchString(word + " ")
+  }
+  if special {
+    return "\\" + word
+  }
+  return word
+}
+
+func formatTodoTxt(t Todo, parentId string, withId bool) string {
+  var parts []string
+  meta := map[string]string{}
+  for k, v := range t.Meta {
+    meta[k] = v
+  }
+
+  created, completed := todoTxtDay(t.CreatedAt), todoTxtDay(t.CompletedAt)
+  if t.Done {
+    parts = append(parts, "x")
+    if completed != "" {
+      parts = append(parts, completed)
+    }
+  } else if t.Priority != "" {
+    parts = append(parts, "(" + t.Priority + ")")
+  }
+  // a done item's creation date has to follow its completion date or it
+  // would be read back as one, so without it it's kept as an extension
+  if created != "" && (!t.Done || completed != "") {
+    parts = append(parts, created)
+  } else if created != "" {
+    meta["created"] = created
+  }
+
+  for i, word := range strings.Fields(t.Name) {
+    parts = append(parts, escapeTodoTxt(word, i == 0))
+  }
+  for _, tag := range t.Tags {
+    if strings.HasPrefix(tag, "@") {
+      parts = append(parts, tag)
+    } else {
+      parts = append(parts, "+" + tag)
+    }
+  }
+
+  keys := make([]string, 0, len(meta))
+  for k := range meta {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  for _, k := range keys {
+    parts = append(parts, k + ":" + meta[k])
+  }
+
+  if t.Due != "" {
+    parts = append(parts, "due:" + t.Due)
+  }
+  if rec, ok := todoTxtRec[t.Repeat]; ok {
+    parts = append(parts, "rec:" + rec)
+  } else if strings.HasPrefix(t.Repeat, "every ") {
+    parts = append(parts, "rec:" + strings.TrimPrefix(t.Repeat, "every "))
+  } else if t.Repeat != "" {
+    parts = append(parts, "repeat:" + strings.ReplaceAll(t.Repeat, " ", "_"))
+  }
+  if t.Done && t.Priority != "" {
+    parts = append(parts, "pri:" + t.Priority)
+  }
+  if withId {
+    parts = append(parts, "id:" + t.Id)
+  }
+  if parentId != "" {
+    parts = append(parts, "parent:" + parentId)
+  }
+  return strings.Join(parts, " ")
+}
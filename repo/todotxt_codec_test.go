@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTodoTxtCodecKeepsText(t *testing.T) {
+  tests := []struct {
+    name string
+    content string
+  }{
+    {"empty", ""},
+    {"plain", "call mom\nbuy milk\n"},
+    {"all the parts", "x 2026-10-18 2026-10-01 call the plumber +house @phone due:2026-10-20\n"},
+    {"priority and created date", "(A) 2026-10-01 pay rent rec:1m\n"},
+    {"extensions", "buy milk +home owner:sam due:2026-10-20\n"},
+    {"other repeat rules", "stretch repeat:weekly_mon,thu\n"},
+    {"done with a priority", "x 2026-10-18 file taxes pri:B\n"},
+    {"done without a completion date", "x file taxes created:2026-10-01\n"},
+    {"children", "plan the trip id:p1\npack parent:p1\nbook flights parent:p1\nwater the plants\n"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := reencode(t, NewTodoTxtCodec(), tt.content); got != tt.content {
+        t.Errorf("re-encoded as\n%s\nwant\n%s", got, tt.content)
+      }
+    })
+  }
+}
+
+func TestTodoTxtCodecDecode(t *testing.T) {
+  todos, err := NewTodoTxtCodec().Decode([]byte("x 2026-10-18 2026-10-01 call the plumber +house @phone due:2026-10-20 rec:1w owner:sam pri:A\n"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []Todo{{
+    Name: "call the plumber",
+    Done: true,
+    Priority: "A",
+    Tags: []string{"house", "@phone"},
+    Due: "2026-10-20",
+    Repeat: "every 1w",
+    CreatedAt: "2026-10-01",
+    CompletedAt: "2026-10-18",
+    Meta: map[string]string{"owner": "sam"},
+  }}
+  if got := withoutIds(todos); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+}
+
+func TestTodoTxtCodecRoundTrip(t *testing.T) {
+  created := Timestamp(time.Date(2026, 10, 1, 9, 30, 0, 0, time.Local))
+  todos := []Todo{
+    {
+      Id: "1",
+      Name: "plan the trip",
+      Priority: "A",
+      Tags: []string{"travel", "@sam"},
+      Due: "2026-10-20",
+      Repeat: "weekly mon,thu",
+      CreatedAt: created,
+      Meta: map[string]string{"owner": "sam"},
+      Children: []Todo{{Id: "2", Name: "pack", Done: true, Priority: "B", CompletedAt: created}},
+    },
+    {Id: "3", Name: "stretch", Repeat: "daily"},
+  }
+  // todo.txt only has days, and nowhere to put notes or history
+  want := []Todo{
+    {
+      Name: "plan the trip",
+      Priority: "A",
+      Tags: []string{"travel", "@sam"},
+      Due: "2026-10-20",
+      Repeat: "weekly mon,thu",
+      CreatedAt: "2026-10-01",
+      Meta: map[string]string{"owner": "sam"},
+      Children: []Todo{{Name: "pack", Done: true, Priority: "B", CompletedAt: "2026-10-01"}},
+    },
+    {Name: "stretch", Repeat: "every 1d"},
+  }
+
+  decoded := roundTrip(t, func() Codec { return NewTodoTxtCodec() }, todos)
+  if got := withoutIds(decoded); !reflect.DeepEqual(got, want) {
+    t.Errorf("decoded %+v, want %+v", got, want)
+  }
+  if decoded[0].Id != "1" {
+    t.Errorf("the parent's id is %q, want it kept in its id: extension", decoded[0].Id)
+  }
+}
+
+func TestTodoTxtCodecKeepsIds(t *testing.T) {
+  c := NewTodoTxtCodec()
+  first, err := c.Decode([]byte("a\nb\na\n"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  first[0].Name = "renamed"
+  content, err := c.Encode(first)
+  if err != nil {
+    t.Fatal(err)
+  }
+  second, err := c.Decode(content)
+  if err != nil {
+    t.Fatal(err)
+  }
+  for i := range first {
+    if first[i].Id != second[i].Id {
+      t.Errorf("item %d changed id from %s to %s", i, first[i].Id, second[i].Id)
+    }
+  }
+}
+
+func TestTodoTxtCodecEscapesNames(t *testing.T) {
+  tests := []struct {
+    name string
+    done bool
+  }{
+    {name: "meet at 10:30 re: x"},
+    {name: "x marks the spot"},
+    {name: "x marks the spot", done: true},
+    {name: "(A) thing"},
+    {name: "(A) thing", done: true},
+    {name: "2026-01-01 party"},
+    {name: "2026-01-01 party", done: true},
+    {name: "email +alice"},
+    {name: "ask @bob"},
+    {name: `a \ backslash and \n`},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      todos := []Todo{{Id: "1", Name: tt.name, Done: tt.done}}
+      want := []Todo{{Name: tt.name, Done: tt.done}}
+      if got := withoutIds(roundTrip(t, func() Codec { return NewTodoTxtCodec() }, todos)); !reflect.DeepEqual(got, want) {
+        t.Errorf("decoded %+v, want %+v", got, want)
+      }
+    })
+  }
+}
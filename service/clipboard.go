@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// Subtree returns a copy of an item and everything under it.
+func (s *Service) Subtree(itemId string) (repo.Todo, bool) {
+  item := s.find(itemId)
+  if item == nil {
+    return repo.Todo{}, false
+  }
+  return repo.Clone([]repo.Todo{*item})[0], true
+}
+
+// Cut takes an item and everything under it out of the tree and returns it
+// for pasting elsewhere.
+func (s *Service) Cut(itemId string) (repo.Todo, error) {
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(itemId, nil)
+  if found == nil {
+    return repo.Todo{}, nil
+  }
+
+  item := *found
+  list := s.siblings(parent)
+  i := indexOf(*list, itemId)
+  *list = append((*list)[:i], (*list)[i+1:]...)
+  return item, s.commit("cut '" + item.Name + "'", before)
+}
+
+// Paste puts a subtree right after target, or as its last child. With copy
+// set, or when the subtree is still in the tree, a copy with fresh ids is
+// pasted so no two items share an id. A nil target pastes at the end of the
+// top level. It returns the id of the pasted item.
+func (s *Service) Paste(target *repo.Todo, item repo.Todo, asChild, copy bool) (string, error) {
+  before := s.snapshot()
+  copy = copy || s.find(item.Id) != nil
+  if copy {
+    item = repo.CopyWithNewIds([]repo.Todo{item})[0]
+  } else {
+    item = repo.Clone([]repo.Todo{item})[0]
+  }
+
+  var parent *repo.Todo
+  if target != nil {
+    var found *repo.Todo
+    parent, found = s.findItemAndParent(target.Id, nil)
+    if found != nil && asChild {
+      parent = found
+      found.Expanded = true
+    }
+  }
+  if !copy {
+    item.Record(time.Now(), repo.EventMoved, "", parentName(parent))
+  }
+  afterId := ""
+  if target != nil && !asChild {
+    afterId = target.Id
+  }
+  s.insertAfter(parent, afterId, item)
+  return item.Id, s.commit("paste '" + item.Name + "'", before)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// ids lists the ids in todos and all their children.
+func ids(todos []repo.Todo) []string {
+  var all []string
+  for _, t := range todos {
+    all = append(all, t.Id)
+    all = append(all, ids(t.Children)...)
+  }
+  return all
+}
+
+func TestPaste(t *testing.T) {
+  tests := []struct {
+    name string
+    cut bool
+    id string
+    target *repo.Todo
+    asChild bool
+    copy bool
+    want string
+    wantNewIds bool
+  }{
+    {name: "cut and paste after", cut: true, id: "b", target: &repo.Todo{Id: "d"}, want: "a(c) d b"},
+    {name: "cut and paste as a child", cut: true, id: "b", target: &repo.Todo{Id: "d"}, asChild: true, want: "a(c) d(b)"},
+    {name: "cut and paste at the end", cut: true, id: "b", want: "a(c) d b"},
+    {name: "cut and paste as a copy", cut: true, id: "b", target: &repo.Todo{Id: "a"}, copy: true, want: "a(c) b d", wantNewIds: true},
+    {name: "paste what is still there", id: "a", target: &repo.Todo{Id: "d"}, want: "a(b c) d a(b c)", wantNewIds: true},
+    {name: "paste a child as the last child", id: "b", target: &repo.Todo{Id: "a"}, asChild: true, want: "a(b c b) d", wantNewIds: true},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s, _ := newTestService(t, item("a", item("b"), item("c")), item("d"))
+      original := ids(s.repo.Todos)
+
+      var subtree repo.Todo
+      if tt.cut {
+        var err error
+        if subtree, err = s.Cut(tt.id); err != nil {
+          t.Fatal(err)
+        }
+      } else {
+        subtree, _ = s.Subtree(tt.id)
+      }
+
+      pasted, err := s.Paste(tt.target, subtree, tt.asChild, tt.copy)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      if gotNewId := pasted != tt.id; gotNewId != tt.wantNewIds {
+        t.Errorf("pasted item has id %q, copied from %q", pasted, tt.id)
+      }
+
+      seen := map[string]bool{}
+      for _, id := range ids(s.repo.Todos) {
+        if seen[id] {
+          t.Errorf("id %q is used twice", id)
+        }
+        seen[id] = true
+      }
+      if tt.wantNewIds {
+        for _, id := range ids([]repo.Todo{*s.find(pasted)}) {
+          for _, o := range original {
+            if id == o {
+              t.Errorf("the copy kept id %q", id)
+            }
+          }
+        }
+      }
+    })
+  }
+}
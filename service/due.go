@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+var weekdays = map[string]time.Weekday{
+  "sun": time.Sunday,
+  "mon": time.Monday,
+  "tue": time.Tuesday,
+  "wed": time.Wednesday,
+  "thu": time.Thursday,
+  "fri": time.Friday,
+  "sat": time.Saturday,
+}
+
+// ParseDue turns user input like "tomorrow", "fri", "+3d" or "2026-11-01"
+// into a date in repo.DateFormat. Weekdays mean the next such day after
+// today. An empty input or "none" clears the date and returns "".
+func ParseDue(input string, now time.Time) (string, error) {
+  input = strings.ToLower(strings.TrimSpace(input))
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+  switch input {
+  case "", "none":
+    return "", nil
+  case "today", "tod":
+    return today.Format(repo.DateFormat), nil
+  case "tomorrow", "tom", "tmr":
+    return today.AddDate(0, 0, 1).Format(repo.DateFormat), nil
+  case "yesterday":
+    return today.AddDate(0, 0, -1).Format(repo.DateFormat), nil
+  }
+
+  if len(input) >= 3 {
+    if day, ok := weekdays[input[:3]]; ok {
+      days := (int(day) - int(today.Weekday()) + 7) % 7
+      if days == 0 {
+        days = 7
+      }
+      return today.AddDate(0, 0, days).Format(repo.DateFormat), nil
+    }
+  }
+
+  if offset := strings.TrimPrefix(input, "+"); len(offset) >= 2 {
+    if n, err := strconv.Atoi(offset[:len(offset)-1]); err == nil {
+      switch offset[len(offset)-1] {
+      case 'd':
+        return today.AddDate(0, 0, n).Format(repo.DateFormat), nil
+      case 'w':
+        return today.AddDate(0, 0, 7*n).Format(repo.DateFormat), nil
+      case 'm':
+        return today.AddDate(0, n, 0).Format(repo.DateFormat), nil
+      case 'y':
+        return today.AddDate(n, 0, 0).Format(repo.DateFormat), nil
+      }
+    }
+  }
+
+  if date, err := time.Parse(repo.DateFormat, input); err == nil {
+    return date.Format(repo.DateFormat), nil
+  }
+  return "", fmt.Errorf("can't understand due date %q, try tomorrow, fri, +3d or 2026-11-01", input)
+}
+
+// DueDate is the date shown for an item: its own due date or, for parents,
+// the earliest due date among it and its unfinished children.
+func (s *Service) DueDate(item repo.Todo) string {
+  due := item.Due
+  for _, child := range item.Children {
+    if s.isAllDone(child) && !s.isAllDone(item) {
+      continue
+    }
+    if childDue := s.DueDate(child); childDue != "" && (due == "" || childDue < due) {
+      due = childDue
+    }
+  }
+  return due
+}
+
+// SetDue sets the due date of an item, an empty due clears it.
+func (s *Service) SetDue(item repo.Todo, due string) error {
+  before := s.snapshot()
+  if t := s.find(item.Id); t != nil {
+    t.Due = due
+    touch(t, time.Now())
+    return s.commit("set due date of '" + t.Name + "'", before)
+  }
+  return nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+  // a Sunday afternoon
+  now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+  tests := []struct {
+    input string
+    want string
+    wantErr bool
+  }{
+    {input: "", want: ""},
+    {input: "none", want: ""},
+    {input: "today", want: "2026-10-18"},
+    {input: " Tomorrow ", want: "2026-10-19"},
+    {input: "tmr", want: "2026-10-19"},
+    {input: "yesterday", want: "2026-10-17"},
+    {input: "mon", want: "2026-10-19"},
+    {input: "Friday", want: "2026-10-23"},
+    {input: "sun", want: "2026-10-25"},
+    {input: "+3d", want: "2026-10-21"},
+    {input: "2w", want: "2026-11-01"},
+    {input: "+1m", want: "2026-11-18"},
+    {input: "1y", want: "2027-10-18"},
+    {input: "-1d", want: "2026-10-17"},
+    {input: "2026-11-01", want: "2026-11-01"},
+    {input: "someday", wantErr: true},
+    {input: "2026-13-01", wantErr: true},
+    {input: "+d", wantErr: true},
+    {input: "3x", wantErr: true},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.input, func(t *testing.T) {
+      got, err := ParseDue(tt.input, now)
+      if (err != nil) != tt.wantErr {
+        t.Fatalf("ParseDue(%q) returned error %v", tt.input, err)
+      }
+      if got != tt.want {
+        t.Errorf("ParseDue(%q) = %q, want %q", tt.input, got, tt.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+const outlineHeader = "<!-- One item per line, indent to nest. Removed lines are deleted, removing them all changes nothing. -->\n\n"
+
+// EditText is an item as text for an editor: the name and tags on the first
+// line and the notes below.
+func (s *Service) EditText(item repo.Todo) string {
+  t := s.find(item.Id)
+  if t == nil {
+    return ""
+  }
+  text := repo.JoinTags(t.Name, t.Tags) + "\n"
+  if t.Notes != "" {
+    text += "\n" + t.Notes + "\n"
+  }
+  return text
+}
+
+// ApplyEditText is the reverse of EditText.
+func (s *Service) ApplyEditText(item repo.Todo, text string) error {
+  lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+  name := strings.TrimSpace(lines[0])
+  if name == "" {
+    return errors.New("the name on the first line is empty, nothing was changed")
+  }
+
+  before := s.snapshot()
+  t := s.find(item.Id)
+  if t == nil {
+    return nil
+  }
+  rename(t, name, time.Now())
+  t.Notes = strings.Trim(strings.Join(lines[1:], "\n"), " \t\n")
+  return s.commit("edit '" + item.Name + "'", before)
+}
+
+// OutlineText is an item and everything under it as a markdown checklist,
+// with priorities, due dates and repeat rules but without the timestamps
+// and history.
+func (s *Service) OutlineText(item repo.Todo) (string, error) {
+  t := s.find(item.Id)
+  if t == nil {
+    return "", nil
+  }
+  content, err := repo.NewMarkdownCodec().Encode([]repo.Todo{*t})
+  if err != nil {
+    return "", err
+  }
+  return outlineHeader + string(content), nil
+}
+
+// ApplyOutlineText replaces an item and its subtree with the checklist in
+// text. Lines are matched back to the items they came from by name, then in
+// order among what's left under the same parent, so those keep their id,
+// history and everything else the checklist doesn't show. Lines that
+// match nothing become new items and items without a line are deleted.
+// An empty checklist is taken as cancelling the edit rather than deleting
+// the item.
+// Completing a recurring item adds its next occurrence, as ToggleTodo does.
+func (s *Service) ApplyOutlineText(item repo.Todo, text string) error {
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil {
+    return nil
+  }
+  edited, err := repo.NewMarkdownCodec().Decode([]byte(text))
+  if err != nil {
+    return err
+  }
+  if len(edited) == 0 {
+    return errors.New("the outline is empty, nothing was changed")
+  }
+  if err := checkOutline(edited); err != nil {
+    return err
+  }
+
+  now := time.Now()
+  var completed []string
+  replacement := rebuildOutline(edited, matchOutline([]repo.Todo{*found}, edited), now, &completed)
+
+  scope := s.siblings(parent)
+  for i := range *scope {
+    if (*scope)[i].Id == item.Id {
+      *scope = append(append((*scope)[:i:i], replacement...), (*scope)[i+1:]...)
+      break
+    }
+  }
+  var repeatErr error
+  for _, id := range completed {
+    if err := s.repeat(id, now); err != nil && repeatErr == nil {
+      repeatErr = err
+    }
+  }
+  if err := s.commit("edit subtree of '" + item.Name + "'", before); err != nil {
+    return err
+  }
+  return repeatErr
+}
+
+// matchOutline maps the ids of edited items to the original items they
+// stand for.
+func matchOutline(original, edited []repo.Todo) map[string]*repo.Todo {
+  matched := map[string]*repo.Todo{}
+  used := map[string]bool{}
+
+  byName := map[string][]*repo.Todo{}
+  var index func(todos []repo.Todo)
+  index = func(todos []repo.Todo) {
+    for i := range todos {
+      byName[todos[i].Name] = append(byName[todos[i].Name], &todos[i])
+      index(todos[i].Children)
+    }
+  }
+  index(original)
+
+  var matchByName func(todos []repo.Todo)
+  matchByName = func(todos []repo.Todo) {
+    for _, e := range todos {
+      for _, o := range byName[e.Name] {
+        if !used[o.Id] {
+          matched[e.Id] = o
+          used[o.Id] = true
+          break
+        }
+      }
+      matchByName(e.Children)
+    }
+  }
+  matchByName(edited)
+
+  // what's left under the same parent was renamed
+  var matchByPosition func(edited, original []repo.Todo)
+  matchByPosition = func(edited, original []repo.Todo) {
+    next := 0
+    for _, e := range edited {
+      for matched[e.Id] == nil && next < len(original) {
+        if !used[original[next].Id] {
+          matched[e.Id] = &original[next]
+          used[original[next].Id] = true
+        }
+        next++
+      }
+      var children []repo.Todo
+      if o := matched[e.Id]; o != nil {
+        children = o.Children
+      }
+      matchByPosition(e.Children, children)
+    }
+  }
+  matchByPosition(edited, original)
+
+  return matched
+}
+
+// rebuildOutline turns the edited checklist back into items, adding the ids
+// of the ones it completes to completed.
+func rebuildOutline(edited []repo.Todo, matched map[string]*repo.Todo, now time.Time, completed *[]string) []repo.Todo {
+  var todos []repo.Todo
+  for _, e := range edited {
+    text := repo.JoinTags(e.Name, e.Tags)
+    var t repo.Todo
+    if o := matched[e.Id]; o != nil {
+      t = *o
+      t.Expanded = t.Expanded || (len(o.Children) == 0 && len(e.Children) > 0)
+      if text != repo.JoinTags(t.Name, t.Tags) {
+        rename(&t, text, now)
+      }
+      if e.Notes != t.Notes || e.Priority != t.Priority || e.Due != t.Due || e.Repeat != t.Repeat {
+        t.Notes, t.Priority, t.Due, t.Repeat = e.Notes, e.Priority, e.Due, e.Repeat
+        touch(&t, now)
+      }
+    } else {
+      t = newTodo(text, now)
+      t.Notes, t.Priority, t.Due, t.Repeat = e.Notes, e.Priority, e.Due, e.Repeat
+      t.Expanded = true
+    }
+    if e.Done && !t.Done {
+      *completed = append(*completed, t.Id)
+    }
+    setDone(&t, e.Done, now)
+    t.Children = rebuildOutline(e.Children, matched, now, completed)
+    todos = append(todos, t)
+  }
+  return todos
+}
+
+// checkOutline makes sure the due dates and repeat rules in an edited
+// checklist make sense before anything is changed, putting the rules in the
+// form ParseRepeat returns.
+func checkOutline(edited []repo.Todo) error {
+  for i := range edited {
+    e := &edited[i]
+    if e.Due != "" {
+      if _, err := time.Parse(repo.DateFormat, e.Due); err != nil {
+        return fmt.Errorf("the due date of '%s' isn't a date, nothing was changed", e.Name)
+      }
+    }
+    if e.Repeat != "" {
+      rule, err := ParseRepeat(e.Repeat)
+      if err != nil {
+        return fmt.Errorf("the repeat rule of '%s': %w, nothing was changed", e.Name, err)
+      }
+      e.Repeat = rule
+    }
+    if err := checkOutline(e.Children); err != nil {
+      return err
+    }
+  }
+  return nil
+}
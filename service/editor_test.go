@@ -0,0 +1,135 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+func TestApplyOutlineText(t *testing.T) {
+  tests := []struct {
+    name string
+    todos []repo.Todo
+    text string
+    want string
+    // wantIds lists the ids after the edit, "new" for the items it added
+    wantIds []string
+  }{
+    {
+      name: "unchanged",
+      text: "- [ ] a\n  - [ ] b\n  - [ ] c\n  - [ ] d\n",
+      want: "a(b c d) e",
+      wantIds: []string{"a", "b", "c", "d", "e"},
+    },
+    {
+      name: "rename",
+      text: "- [ ] a\n  - [ ] b\n  - [ ] C\n  - [ ] d\n",
+      want: "a(b C d) e",
+      wantIds: []string{"a", "b", "c", "d", "e"},
+    },
+    {
+      name: "reorder",
+      text: "- [ ] a\n  - [ ] d\n  - [ ] b\n  - [ ] c\n",
+      want: "a(d b c) e",
+      wantIds: []string{"a", "d", "b", "c", "e"},
+    },
+    {
+      name: "reorder and rename",
+      text: "- [ ] a\n  - [ ] d\n  - [ ] B\n  - [ ] c\n",
+      want: "a(d B c) e",
+      wantIds: []string{"a", "d", "b", "c", "e"},
+    },
+    {
+      name: "nest",
+      text: "- [ ] a\n  - [ ] b\n    - [ ] d\n  - [ ] c\n",
+      want: "a(b(d) c) e",
+      wantIds: []string{"a", "b", "d", "c", "e"},
+    },
+    {
+      name: "delete a line",
+      text: "- [ ] a\n  - [ ] b\n  - [ ] d\n",
+      want: "a(b d) e",
+      wantIds: []string{"a", "b", "d", "e"},
+    },
+    {
+      name: "delete every child",
+      text: "- [ ] a\n",
+      want: "a e",
+      wantIds: []string{"a", "e"},
+    },
+    {
+      name: "add a line",
+      text: "- [ ] a\n  - [ ] b\n  - [ ] c\n  - [ ] new\n  - [ ] d\n",
+      want: "a(b c new d) e",
+      wantIds: []string{"a", "b", "c", "new", "d", "e"},
+    },
+    {
+      name: "duplicate a line",
+      text: "- [ ] a\n  - [ ] b\n  - [ ] b\n  - [ ] c\n  - [ ] d\n",
+      want: "a(b b c d) e",
+      wantIds: []string{"a", "b", "new", "c", "d", "e"},
+    },
+    {
+      name: "duplicate names keep their order",
+      todos: []repo.Todo{item("a", item("b"), repo.Todo{Id: "b2", Name: "b"}, item("c")), item("e")},
+      text: "- [ ] a\n  - [ ] c\n  - [ ] b\n  - [ ] b\n",
+      want: "a(c b b) e",
+      wantIds: []string{"a", "c", "b", "b2", "e"},
+    },
+    {
+      name: "delete one of two with the same name",
+      todos: []repo.Todo{item("a", item("b"), repo.Todo{Id: "b2", Name: "b"}, item("c")), item("e")},
+      text: "- [ ] a\n  - [ ] b\n  - [ ] c\n",
+      want: "a(b c) e",
+      wantIds: []string{"a", "b", "c", "e"},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      todos := tt.todos
+      if todos == nil {
+        todos = []repo.Todo{item("a", item("b"), item("c"), item("d")), item("e")}
+      }
+      original := map[string]bool{}
+      for _, id := range ids(todos) {
+        original[id] = true
+      }
+      s, _ := newTestService(t, todos...)
+
+      if err := s.ApplyOutlineText(repo.Todo{Id: "a", Name: "a"}, outlineHeader + tt.text); err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      var got []string
+      for _, id := range ids(s.repo.Todos) {
+        if !original[id] {
+          id = "new"
+        }
+        got = append(got, id)
+      }
+      if !reflect.DeepEqual(got, tt.wantIds) {
+        t.Errorf("ids are %v, want %v", got, tt.wantIds)
+      }
+    })
+  }
+}
+
+func TestApplyEmptyOutlineText(t *testing.T) {
+  for _, text := range []string{"", outlineHeader, "\n  \n"} {
+    s, store := newTestService(t, item("a", item("b")), item("c"))
+    if err := s.ApplyOutlineText(repo.Todo{Id: "a", Name: "a"}, text); err == nil {
+      t.Errorf("applying %q returned no error", text)
+    }
+    saved, _ := store.Load()
+    if got := outline(saved); got != "a(b) c" {
+      t.Errorf("applying %q left %q, want the todos unchanged", text, got)
+    }
+    if label, _ := s.Undo(); label != "" {
+      t.Errorf("applying %q can be undone as %q", text, label)
+    }
+  }
+}
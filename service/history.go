@@ -0,0 +1,98 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jquag/tui-do/repo"
+)
+
+// Completion groups, from most to least recent.
+const (
+  CompletedToday = "Today"
+  CompletedThisWeek = "This week"
+  CompletedEarlier = "Older"
+)
+
+func newTodo(name string, now time.Time) repo.Todo {
+  name, tags := repo.SplitTags(name)
+  t := repo.Todo{
+    Id: uuid.New().String(),
+    Name: name,
+    Tags: tags,
+    CreatedAt: repo.Timestamp(now),
+  }
+  t.Record(now, repo.EventCreated, "", "")
+  return t
+}
+
+// rename sets the name and tags of t from text with #tags and @mentions.
+func rename(t *repo.Todo, text string, now time.Time) {
+  name, tags := repo.SplitTags(text)
+  if name != t.Name {
+    t.Record(now, repo.EventRenamed, t.Name, name)
+  }
+  t.Name, t.Tags = name, tags
+  touch(t, now)
+}
+
+func setDone(t *repo.Todo, done bool, now time.Time) {
+  if t.Done == done {
+    return
+  }
+  t.Done = done
+  if done {
+    t.CompletedAt = repo.Timestamp(now)
+    t.Record(now, repo.EventCompleted, "", "")
+  } else {
+    t.CompletedAt = ""
+    t.Record(now, repo.EventReopened, "", "")
+  }
+}
+
+func touch(t *repo.Todo, now time.Time) {
+  t.UpdatedAt = repo.Timestamp(now)
+}
+
+// CompletedAt is when an item was finished. For a parent that's when its
+// last child was.
+func (s *Service) CompletedAt(item repo.Todo) time.Time {
+  if len(item.Children) == 0 {
+    at, _ := repo.ParseTimestamp(item.CompletedAt)
+    return at
+  }
+
+  var latest time.Time
+  for _, child := range item.Children {
+    if at := s.CompletedAt(child); at.After(latest) {
+      latest = at
+    }
+  }
+  return latest
+}
+
+// SortByCompletion orders todos by CompletedAt, most recent first. Unlike
+// SortByPriority it returns a sorted copy and persists nothing.
+func (s *Service) SortByCompletion(todos []repo.Todo) []repo.Todo {
+  sorted := append([]repo.Todo{}, todos...)
+  sort.SliceStable(sorted, func(i, j int) bool {
+    return s.CompletedAt(sorted[i]).After(s.CompletedAt(sorted[j]))
+  })
+  return sorted
+}
+
+// CompletionGroup says whether an item was completed today, earlier this
+// week or before that.
+func (s *Service) CompletionGroup(item repo.Todo, now time.Time) string {
+  at := s.CompletedAt(item)
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+  weekday := (int(today.Weekday()) + 6) % 7 // days since monday
+  switch {
+  case !at.Before(today):
+    return CompletedToday
+  case !at.Before(today.AddDate(0, 0, -weekday)):
+    return CompletedThisWeek
+  }
+  return CompletedEarlier
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// MoveUp swaps an item with the sibling above it. At the top level only
+// items on the same tab count as siblings, so the move is always visible.
+func (s *Service) MoveUp(item repo.Todo) error {
+  return s.move(item, -1, "up")
+}
+
+// MoveDown swaps an item with the sibling below it.
+func (s *Service) MoveDown(item repo.Todo) error {
+  return s.move(item, 1, "down")
+}
+
+func (s *Service) move(item repo.Todo, by int, direction string) error {
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil {
+    return nil
+  }
+
+  list := *s.siblings(parent)
+  from := indexOf(list, item.Id)
+  to := from + by
+  for parent == nil && to >= 0 && to < len(list) && s.isAllDone(list[to]) != s.isAllDone(list[from]) {
+    to += by
+  }
+  if to < 0 || to >= len(list) {
+    return nil
+  }
+
+  list[from].Record(time.Now(), repo.EventMoved, "", "")
+  list[from], list[to] = list[to], list[from]
+  return s.commit("move '" + item.Name + "' " + direction, before)
+}
+
+func indexOf(todos []repo.Todo, itemId string) int {
+  for i := range todos {
+    if todos[i].Id == itemId {
+      return i
+    }
+  }
+  return -1
+}
+
+// Indent makes an item the last child of the sibling above it, expanding
+// that sibling so the item stays in view.
+func (s *Service) Indent(item repo.Todo) error {
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil {
+    return nil
+  }
+
+  list := s.siblings(parent)
+  from := indexOf(*list, item.Id)
+  to := from - 1
+  for parent == nil && to >= 0 && s.isAllDone((*list)[to]) != s.isAllDone((*list)[from]) {
+    to--
+  }
+  if to < 0 {
+    return nil
+  }
+
+  moved := (*list)[from]
+  *list = append((*list)[:from], (*list)[from+1:]...)
+  newParent := &(*list)[to]
+  moved.Record(time.Now(), repo.EventMoved, parentName(parent), newParent.Name)
+  newParent.Children = append(newParent.Children, moved)
+  newParent.Expanded = true
+  return s.commit("indent '" + item.Name + "'", before)
+}
+
+// Outdent moves an item out of its parent to right after it.
+func (s *Service) Outdent(item repo.Todo) error {
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil || parent == nil {
+    return nil
+  }
+  grandparent, _ := s.findItemAndParent(parent.Id, nil)
+
+  moved := *found
+  from := indexOf(parent.Children, item.Id)
+  parent.Children = append(parent.Children[:from], parent.Children[from+1:]...)
+  moved.Record(time.Now(), repo.EventMoved, parent.Name, parentName(grandparent))
+  s.insertAfter(grandparent, parent.Id, moved)
+  return s.commit("outdent '" + item.Name + "'", before)
+}
+
+func parentName(parent *repo.Todo) string {
+  if parent == nil {
+    return ""
+  }
+  return parent.Name
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// moveFixture is "a(b c d) e x:f g".
+func moveFixture() []repo.Todo {
+  f := item("f")
+  f.Done = true
+  return []repo.Todo{item("a", item("b"), item("c"), item("d")), item("e"), f, item("g")}
+}
+
+func TestMoveUpAndDown(t *testing.T) {
+  tests := []struct {
+    name string
+    move func(s *Service, item repo.Todo) error
+    id string
+    want string
+  }{
+    {"child up", (*Service).MoveUp, "c", "a(c b d) e x:f g"},
+    {"first child up", (*Service).MoveUp, "b", "a(b c d) e x:f g"},
+    {"child down", (*Service).MoveDown, "b", "a(c b d) e x:f g"},
+    {"last child down", (*Service).MoveDown, "d", "a(b c d) e x:f g"},
+    {"top level up", (*Service).MoveUp, "e", "e a(b c d) x:f g"},
+    {"first item up", (*Service).MoveUp, "a", "a(b c d) e x:f g"},
+    {"down past a done item", (*Service).MoveDown, "e", "a(b c d) g x:f e"},
+    {"up past a done item", (*Service).MoveUp, "g", "a(b c d) g x:f e"},
+    {"last item down", (*Service).MoveDown, "g", "a(b c d) e x:f g"},
+    {"done item without done neighbours", (*Service).MoveDown, "f", "a(b c d) e x:f g"},
+    {"missing item", (*Service).MoveUp, "z", "a(b c d) e x:f g"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s, store := newTestService(t, moveFixture()...)
+      if err := tt.move(s, repo.Todo{Id: tt.id, Name: tt.id}); err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      saved, _ := store.Load()
+      if got := outline(saved); got != tt.want {
+        t.Errorf("saved todos are %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestIndentAndOutdent(t *testing.T) {
+  tests := []struct {
+    name string
+    move func(s *Service, item repo.Todo) error
+    id string
+    want string
+  }{
+    {"indent a child", (*Service).Indent, "c", "a(b(c) d) e x:f g"},
+    {"indent a first child", (*Service).Indent, "b", "a(b c d) e x:f g"},
+    {"indent under an item with children", (*Service).Indent, "e", "a(b c d e) x:f g"},
+    {"indent past a done item", (*Service).Indent, "g", "a(b c d) e(g) x:f"},
+    {"indent the first item", (*Service).Indent, "a", "a(b c d) e x:f g"},
+    {"outdent a middle child", (*Service).Outdent, "c", "a(b d) c e x:f g"},
+    {"outdent the last child", (*Service).Outdent, "d", "a(b c) d e x:f g"},
+    {"outdent a top level item", (*Service).Outdent, "e", "a(b c d) e x:f g"},
+    {"missing item", (*Service).Indent, "z", "a(b c d) e x:f g"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s, store := newTestService(t, moveFixture()...)
+      if err := tt.move(s, repo.Todo{Id: tt.id, Name: tt.id}); err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      saved, _ := store.Load()
+      if got := outline(saved); got != tt.want {
+        t.Errorf("saved todos are %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestIndentRecordsTheMove(t *testing.T) {
+  s, _ := newTestService(t, moveFixture()...)
+
+  if err := s.Indent(repo.Todo{Id: "c", Name: "c"}); err != nil {
+    t.Fatal(err)
+  }
+  if !s.find("b").Expanded {
+    t.Error("the new parent wasn't expanded")
+  }
+  want := repo.Event{At: s.find("c").UpdatedAt, Action: repo.EventMoved, From: "a", To: "b"}
+  if history := s.find("c").History; len(history) != 1 || history[0] != want {
+    t.Errorf("history is %+v, want %+v", history, want)
+  }
+
+  if err := s.Outdent(repo.Todo{Id: "c", Name: "c"}); err != nil {
+    t.Fatal(err)
+  }
+  want = repo.Event{At: s.find("c").UpdatedAt, Action: repo.EventMoved, From: "b", To: "a"}
+  if history := s.find("c").History; len(history) != 2 || history[1] != want {
+    t.Errorf("history is %+v, want %+v last", history, want)
+  }
+}
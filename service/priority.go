@@ -0,0 +1,87 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// RaisePriority moves an item one level up: none becomes low ("C") and
+// every letter becomes the one before it, up to "A".
+func (s *Service) RaisePriority(item repo.Todo) error {
+  before := s.snapshot()
+  t := s.find(item.Id)
+  if t == nil {
+    return nil
+  }
+
+  switch {
+  case t.Priority == "":
+    t.Priority = repo.PriorityLow
+  case t.Priority > repo.PriorityHigh:
+    t.Priority = string(t.Priority[0] - 1)
+  default:
+    return nil
+  }
+  touch(t, time.Now())
+  return s.commit("raise priority of '" + t.Name + "'", before)
+}
+
+// LowerPriority moves an item one level down: "A" and "B" become the next
+// letter and anything from low ("C") down becomes none.
+func (s *Service) LowerPriority(item repo.Todo) error {
+  before := s.snapshot()
+  t := s.find(item.Id)
+  if t == nil || t.Priority == "" {
+    return nil
+  }
+
+  if t.Priority < repo.PriorityLow {
+    t.Priority = string(t.Priority[0] + 1)
+  } else {
+    t.Priority = ""
+  }
+  touch(t, time.Now())
+  return s.commit("lower priority of '" + t.Name + "'", before)
+}
+
+// SortByPriority reorders the siblings of item, highest priority first.
+// Items with the same priority keep their order. With a nil item every
+// list in the tree is sorted.
+func (s *Service) SortByPriority(item *repo.Todo) error {
+  before := s.snapshot()
+  if item == nil {
+    sortByPriority(s.repo.Todos, true)
+    return s.commit("sort all by priority", before)
+  }
+
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil {
+    return nil
+  }
+  if parent == nil {
+    sortByPriority(s.repo.Todos, false)
+  } else {
+    sortByPriority(parent.Children, false)
+  }
+  return s.commit("sort by priority", before)
+}
+
+func sortByPriority(todos []repo.Todo, recursive bool) {
+  sort.SliceStable(todos, func(i, j int) bool {
+    return priorityRank(todos[i]) < priorityRank(todos[j])
+  })
+  if recursive {
+    for i := range todos {
+      sortByPriority(todos[i].Children, true)
+    }
+  }
+}
+
+func priorityRank(t repo.Todo) int {
+  if t.Priority == "" {
+    return 'Z' + 1
+  }
+  return int(t.Priority[0])
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+var queryTerm = regexp.MustCompile(`^([A-Za-z_][\w-]*)(:|<=|>=|<|>|=)(.*)$`)
+
+// Query is a parsed filter like "tag:backend and not done and due<+7d".
+//
+// Terms are combined with "and", "or", "not" and parentheses, and terms
+// next to each other are and-ed. A term is one of:
+//
+//   done, open, overdue       the item is done, isn't, or is past due
+//   tag:backend, #backend     the item has the tag, @alice for mentions
+//   name:text, text, "a b"    the name contains text, ignoring case
+//   notes:text                the notes contain text
+//   repeat:weekly             the repeat rule contains text
+//   has:due                   the item has a due, priority, tags, notes,
+//                             repeat or children
+//   pri:A, pri<=B             the priority, letters sort A first
+//   due<+7d, created>=-1w     the date compared with anything ParseDue
+//                             understands, also completed and updated
+//   key:value                 any other key is looked up in the Meta
+//
+// Dates are relative to when the query is matched, not parsed, so a saved
+// query keeps meaning the same thing.
+type Query struct {
+  source string
+  root predicate
+}
+
+type predicate func(item repo.Todo, now time.Time) bool
+
+// ParseQuery parses a query, see Query for the syntax.
+func ParseQuery(input string) (*Query, error) {
+  p := &queryParser{tokens: tokenizeQuery(input)}
+  if len(p.tokens) == 0 {
+    return nil, fmt.Errorf("empty query")
+  }
+  root, err := p.or()
+  if err != nil {
+    return nil, err
+  }
+  if p.pos < len(p.tokens) {
+    return nil, fmt.Errorf("unexpected %q in query", p.tokens[p.pos])
+  }
+  return &Query{source: strings.TrimSpace(input), root: root}, nil
+}
+
+// Match reports whether an item itself, not counting its children, matches
+// the query.
+func (q *Query) Match(item repo.Todo, now time.Time) bool {
+  return q.root(item, now)
+}
+
+func (q *Query) String() string {
+  return q.source
+}
+
+// FilterByQuery keeps the items matching q along with their ancestors.
+func FilterByQuery(todos []repo.Todo, q *Query, now time.Time) []repo.Todo {
+  return prune(todos, func(t repo.Todo) bool {
+    return q.Match(t, now)
+  })
+}
+
+func tokenizeQuery(input string) []string {
+  var tokens []string
+  var word strings.Builder
+  quoted := false
+  flush := func() {
+    if word.Len() > 0 {
+      tokens = append(tokens, word.String())
+      word.Reset()
+    }
+  }
+  for _, r := range input {
+    switch {
+    case r == '"':
+      word.WriteRune(r)
+      quoted = !quoted
+    case quoted:
+      word.WriteRune(r)
+    case r == '(' || r == ')':
+      flush()
+      tokens = append(tokens, string(r))
+    case r == ' ' || r == '\t':
+      flush()
+    default:
+      word.WriteRune(r)
+    }
+  }
+  flush()
+  return tokens
+}
+
+type queryParser struct {
+  tokens []string
+  pos int
+}
+
+func (p *queryParser) peek() string {
+  if p.pos < len(p.tokens) {
+    return strings.ToLower(p.tokens[p.pos])
+  }
+  return ""
+}
+
+func (p *queryParser) or() (predicate, error) {
+  left, err := p.and()
+  if err != nil {
+    return nil, err
+  }
+  for p.peek() == "or" {
+    p.pos++
+    right, err := p.and()
+    if err != nil {
+      return nil, err
+    }
+    l := left
+    left = func(item repo.Todo, now time.Time) bool {
+      return l(item, now) || right(item, now)
+    }
+  }
+  return left, nil
+}
+
+func (p *queryParser) and() (predicate, error) {
+  left, err := p.not()
+  if err != nil {
+    return nil, err
+  }
+  for p.peek() != "" && p.peek() != "or" && p.peek() != ")" {
+    if p.peek() == "and" {
+      p.pos++
+    }
+    right, err := p.not()
+    if err != nil {
+      return nil, err
+    }
+    l := left
+    left = func(item repo.Todo, now time.Time) bool {
+      return l(item, now) && right(item, now)
+    }
+  }
+  return left, nil
+}
+
+func (p *queryParser) not() (predicate, error) {
+  switch p.peek() {
+  case "":
+    return nil, fmt.Errorf("query ends too early")
+  case "not":
+    p.pos++
+    inner, err := p.not()
+    if err != nil {
+      return nil, err
+    }
+    return func(item repo.Todo, now time.Time) bool {
+      return !inner(item, now)
+    }, nil
+  case "(":
+    p.pos++
+    inner, err := p.or()
+    if err != nil {
+      return nil, err
+    }
+    if p.peek() != ")" {
+      return nil, fmt.Errorf("missing ) in query")
+    }
+    p.pos++
+    return inner, nil
+  case ")", "and", "or":
+    return nil, fmt.Errorf("unexpected %q in query", p.tokens[p.pos])
+  }
+  token := p.tokens[p.pos]
+  p.pos++
+  return parseQueryTerm(token)
+}
+
+func parseQueryTerm(token string) (predicate, error) {
+  switch strings.ToLower(token) {
+  case "done":
+    return func(item repo.Todo, now time.Time) bool { return item.Done }, nil
+  case "open":
+    return func(item repo.Todo, now time.Time) bool { return !item.Done }, nil
+  case "overdue":
+    return func(item repo.Todo, now time.Time) bool {
+      return !item.Done && item.Due != "" && item.Due < now.Format(repo.DateFormat)
+    }, nil
+  }
+
+  m := queryTerm.FindStringSubmatch(token)
+  if m == nil {
+    if name, tags := repo.SplitTags(token); name == "" && len(tags) == 1 {
+      return tagTerm(tags[0]), nil
+    }
+    return containsTerm(func(item repo.Todo) string { return item.Name }, unquote(token)), nil
+  }
+
+  field, op, value := strings.ToLower(m[1]), m[2], unquote(m[3])
+  if op == "=" {
+    op = ":"
+  }
+  if value == "" {
+    return nil, fmt.Errorf("%s%s needs a value", field, op)
+  }
+  switch field {
+  case "due", "created", "completed", "updated":
+    return dateTerm(field, op, value)
+  case "pri", "priority":
+    return priorityTerm(op, value)
+  }
+
+  if op != ":" {
+    return nil, fmt.Errorf("%s can't be compared with %s", field, op)
+  }
+  switch field {
+  case "tag":
+    return tagTerm(repo.NormalizeTag(value)), nil
+  case "name":
+    return containsTerm(func(item repo.Todo) string { return item.Name }, value), nil
+  case "notes":
+    return containsTerm(func(item repo.Todo) string { return item.Notes }, value), nil
+  case "repeat":
+    return containsTerm(func(item repo.Todo) string { return item.Repeat }, value), nil
+  case "has":
+    return hasTerm(value)
+  }
+  return func(item repo.Todo, now time.Time) bool {
+    v, ok := item.Meta[field]
+    return ok && strings.EqualFold(v, value)
+  }, nil
+}
+
+func unquote(s string) string {
+  if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+    return s[1:len(s)-1]
+  }
+  return s
+}
+
+func tagTerm(tag string) predicate {
+  return func(item repo.Todo, now time.Time) bool {
+    return item.HasTag(tag)
+  }
+}
+
+func containsTerm(field func(repo.Todo) string, text string) predicate {
+  text = strings.ToLower(text)
+  return func(item repo.Todo, now time.Time) bool {
+    return strings.Contains(strings.ToLower(field(item)), text)
+  }
+}
+
+func hasTerm(what string) (predicate, error) {
+  fields := map[string]func(repo.Todo) bool{
+    "due": func(t repo.Todo) bool { return t.Due != "" },
+    "priority": func(t repo.Todo) bool { return t.Priority != "" },
+    "pri": func(t repo.Todo) bool { return t.Priority != "" },
+    "tags": func(t repo.Todo) bool { return len(t.Tags) > 0 },
+    "notes": func(t repo.Todo) bool { return t.Notes != "" },
+    "repeat": func(t repo.Todo) bool { return t.Repeat != "" },
+    "children": func(t repo.Todo) bool { return len(t.Children) > 0 },
+  }
+  has, ok := fields[strings.ToLower(what)]
+  if !ok {
+    return nil, fmt.Errorf("can't tell if an item has %q, try due, priority, tags, notes, repeat or children", what)
+  }
+  return func(item repo.Todo, now time.Time) bool {
+    return has(item)
+  }, nil
+}
+
+// compare applies a query operator to two strings that sort like the values
+// they stand for.
+func compare(a, op, b string) bool {
+  switch op {
+  case "<":
+    return a < b
+  case "<=":
+    return a <= b
+  case ">":
+    return a > b
+  case ">=":
+    return a >= b
+  }
+  return a == b
+}
+
+func priorityTerm(op, value string) (predicate, error) {
+  value = strings.ToUpper(value)
+  if value == "NONE" && op == ":" {
+    return func(item repo.Todo, now time.Time) bool { return item.Priority == "" }, nil
+  }
+  if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+    return nil, fmt.Errorf("priority %q isn't a letter from A to Z", value)
+  }
+  return func(item repo.Todo, now time.Time) bool {
+    return item.Priority != "" && compare(item.Priority, op, value)
+  }, nil
+}
+
+func dateTerm(field, op, value string) (predicate, error) {
+  if strings.EqualFold(value, "none") {
+    if op != ":" {
+      return nil, fmt.Errorf("%s can't be compared with none", field)
+    }
+  } else if _, err := ParseDue(value, time.Now()); err != nil {
+    return nil, fmt.Errorf("can't understand %s date %q, try today, +7d, -1w or 2026-11-01", field, value)
+  }
+
+  date := func(item repo.Todo) string {
+    switch field {
+    case "due":
+      return item.Due
+    case "created":
+      return localDate(item.CreatedAt)
+    case "completed":
+      return localDate(item.CompletedAt)
+    }
+    return localDate(item.UpdatedAt)
+  }
+  return func(item repo.Todo, now time.Time) bool {
+    want, _ := ParseDue(value, now)
+    have := date(item)
+    if want == "" {
+      return have == ""
+    }
+    return have != "" && compare(have, op, want)
+  }, nil
+}
+
+// localDate is the day of a timestamp in repo.DateFormat, or "" if there is
+// none.
+func localDate(timestamp string) string {
+  t, ok := repo.ParseTimestamp(timestamp)
+  if !ok {
+    return ""
+  }
+  return t.Local().Format(repo.DateFormat)
+}
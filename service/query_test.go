@@ -0,0 +1,101 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+func TestParseQuery(t *testing.T) {
+  now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+  todos := []repo.Todo{
+    {
+      Id: "a",
+      Name: "pay rent",
+      Tags: []string{"home"},
+      Priority: "A",
+      Due: "2026-10-17",
+      Meta: map[string]string{"owner": "sam"},
+    },
+    {
+      Id: "b",
+      Name: "Write migration",
+      Tags: []string{"backend", "@alice"},
+      Priority: "C",
+      Due: "2026-10-22",
+      Notes: "see the schema doc",
+      Repeat: "weekly mon",
+    },
+    {
+      Id: "c",
+      Name: "file taxes",
+      Done: true,
+      Due: "2026-10-01",
+      CompletedAt: repo.Timestamp(time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)),
+    },
+  }
+
+  tests := []struct {
+    query string
+    want string
+  }{
+    {"done", "c"},
+    {"open", "a b"},
+    {"overdue", "a"},
+    {"tag:home", "a"},
+    {"#backend", "b"},
+    {"@alice", "b"},
+    {"tag:#Backend", "b"},
+    {"rent", "a"},
+    {`"pay rent"`, "a"},
+    {"name:WRITE", "b"},
+    {"notes:schema", "b"},
+    {"repeat:weekly", "b"},
+    {"has:due", "a b c"},
+    {"has:notes", "b"},
+    {"pri:A", "a"},
+    {"pri<=B", "a"},
+    {"pri>A", "b"},
+    {"pri:none", "c"},
+    {"due<today", "a c"},
+    {"due<+7d", "a b c"},
+    {"due>=2026-10-20", "b"},
+    {"due:none", ""},
+    {"completed:today", "c"},
+    {"owner:sam", "a"},
+    {"owner=SAM", "a"},
+    {"tag:home or tag:backend", "a b"},
+    {"open and not overdue", "b"},
+    {"open not overdue", "b"},
+    {"not (done or overdue)", "b"},
+    {"(tag:home or done) pri:A", "a"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.query, func(t *testing.T) {
+      q, err := ParseQuery(tt.query)
+      if err != nil {
+        t.Fatal(err)
+      }
+      var matched []string
+      for _, todo := range todos {
+        if q.Match(todo, now) {
+          matched = append(matched, todo.Id)
+        }
+      }
+      if got := strings.Join(matched, " "); got != tt.want {
+        t.Errorf("%q matches %q, want %q", tt.query, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestParseQueryErrors(t *testing.T) {
+  for _, query := range []string{"", "(done", "done)", "and done", "done or", "name:", "pri:AB", "due<someday", "due<none", "has:wings", "tag<x"} {
+    if _, err := ParseQuery(query); err == nil {
+      t.Errorf("ParseQuery(%q) didn't fail", query)
+    }
+  }
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jquag/tui-do/repo"
+)
+
+// how far ahead NextDue looks for a day a rule falls on
+const repeatHorizon = 5 * 366
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseRepeat checks a recurrence rule and returns it in canonical form.
+// It understands:
+//
+//   daily
+//   weekly                 every 7 days from the due date
+//   weekly mon,thu         on those weekdays, also "weekdays"
+//   monthly                every month on the day of the due date
+//   monthly 15             on that day of the month, or the month's last
+//   every 3d               also w(eeks), m(onths) and y(ears)
+//   cron 0 9 1-7 * mon     the day fields of a cron line: day of month,
+//                          month and weekday, minute and hour are optional
+//
+// An empty input or "none" clears the rule.
+func ParseRepeat(input string) (string, error) {
+  fields := strings.Fields(strings.ToLower(input))
+  if len(fields) == 0 || fields[0] == "none" {
+    return "", nil
+  }
+
+  switch {
+  case len(fields) == 1 && fields[0] == "daily":
+    return "daily", nil
+  case len(fields) == 1 && fields[0] == "yearly":
+    return "every 1y", nil
+  case len(fields) == 1 && fields[0] == "weekdays":
+    return "weekly mon,tue,wed,thu,fri", nil
+  case len(fields) == 1 && (fields[0] == "weekly" || fields[0] == "monthly"):
+    return fields[0], nil
+
+  case len(fields) == 2 && fields[0] == "weekly":
+    days, err := parseWeekdays(fields[1])
+    if err != nil {
+      return "", err
+    }
+    var names []string
+    for _, d := range days {
+      names = append(names, weekdayNames[d])
+    }
+    return "weekly " + strings.Join(names, ","), nil
+
+  case len(fields) == 2 && fields[0] == "monthly":
+    day, err := strconv.Atoi(fields[1])
+    if err != nil || day < 1 || day > 31 {
+      return "", fmt.Errorf("%q is not a day of the month", fields[1])
+    }
+    return "monthly " + strconv.Itoa(day), nil
+
+  case len(fields) >= 2 && fields[0] == "every":
+    n, unit, err := parseInterval(strings.Join(fields[1:], ""))
+    if err != nil {
+      return "", err
+    }
+    return fmt.Sprintf("every %d%c", n, unit), nil
+
+  case fields[0] == "cron" && (len(fields) == 4 || len(fields) == 6):
+    days := fields[len(fields)-3:]
+    if _, err := cronMatcher(days); err != nil {
+      return "", err
+    }
+    return "cron " + strings.Join(days, " "), nil
+  }
+
+  return "", fmt.Errorf("don't know how to repeat %q", input)
+}
+
+// NextDue is the first day a rule falls on after both today and the due
+// date. Rules that count from the due date, like "every 3d", count from
+// today when there is none.
+func NextDue(rule string, due string, now time.Time) (string, error) {
+  rule, err := ParseRepeat(rule)
+  if err != nil || rule == "" {
+    return "", err
+  }
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+  from, err := time.ParseInLocation(repo.DateFormat, due, time.Local)
+  if err != nil {
+    from = today
+  }
+  after := from
+  if today.After(after) {
+    after = today
+  }
+
+  fields := strings.Fields(rule)
+  // nth is the k-th occurrence counting from the due date
+  var nth func(k int) time.Time
+  var matches func(time.Time) bool
+  switch {
+  case rule == "daily":
+    nth = func(k int) time.Time { return from.AddDate(0, 0, k) }
+  case rule == "weekly":
+    nth = func(k int) time.Time { return from.AddDate(0, 0, 7*k) }
+  case rule == "monthly":
+    day := from.Day()
+    matches = func(t time.Time) bool { return t.Day() == clampDay(t, day) }
+  case fields[0] == "weekly":
+    days, err := parseWeekdays(fields[1])
+    if err != nil {
+      return "", err
+    }
+    matches = func(t time.Time) bool {
+      for _, d := range days {
+        if t.Weekday() == d {
+          return true
+        }
+      }
+      return false
+    }
+  case fields[0] == "monthly":
+    day, _ := strconv.Atoi(fields[1])
+    matches = func(t time.Time) bool { return t.Day() == clampDay(t, day) }
+  case fields[0] == "every":
+    n, unit, err := parseInterval(fields[1])
+    if err != nil {
+      return "", err
+    }
+    nth = func(k int) time.Time {
+      switch unit {
+      case 'w':
+        return from.AddDate(0, 0, 7*n*k)
+      case 'm':
+        return addMonths(from, n*k)
+      case 'y':
+        return addMonths(from, 12*n*k)
+      }
+      return from.AddDate(0, 0, n*k)
+    }
+  case fields[0] == "cron":
+    matches, err = cronMatcher(fields[1:])
+    if err != nil {
+      return "", err
+    }
+  default:
+    return "", fmt.Errorf("don't know how to repeat %q", rule)
+  }
+
+  if nth != nil {
+    k := 1
+    for !nth(k).After(after) {
+      k++
+    }
+    return nth(k).Format(repo.DateFormat), nil
+  }
+  for i := 1; i <= repeatHorizon; i++ {
+    if next := after.AddDate(0, 0, i); matches(next) {
+      return next.Format(repo.DateFormat), nil
+    }
+  }
+  return "", fmt.Errorf("%q never comes up", rule)
+}
+
+// SetRepeat sets the recurrence rule of an item, which must already be in
+// the form ParseRepeat returns.
+func (s *Service) SetRepeat(item repo.Todo, rule string) error {
+  before := s.snapshot()
+  if t := s.find(item.Id); t != nil {
+    t.Repeat = rule
+    touch(t, time.Now())
+    return s.commit("set repeat rule of '" + t.Name + "'", before)
+  }
+  return nil
+}
+
+// repeat adds the next occurrence of a recurring item that was just
+// completed right after it. The rule moves to the new occurrence so the
+// completed one can be reopened without repeating twice.
+func (s *Service) repeat(itemId string, now time.Time) error {
+  parent, t := s.findItemAndParent(itemId, nil)
+  if t == nil || !t.Done || t.Repeat == "" {
+    return nil
+  }
+  due, err := NextDue(t.Repeat, t.Due, now)
+  if err != nil {
+    return err
+  }
+
+  next := nextOccurrence(*t, now)
+  next.Due = due
+  t.Repeat = ""
+  s.insertAfter(parent, itemId, next)
+  return nil
+}
+
+func nextOccurrence(t repo.Todo, now time.Time) repo.Todo {
+  t.Id = uuid.New().String()
+  t.Done = false
+  t.CompletedAt = ""
+  t.CreatedAt = repo.Timestamp(now)
+  t.History = nil
+  t.Record(now, repo.EventCreated, "", "")
+  if t.Meta != nil {
+    meta := make(map[string]string, len(t.Meta))
+    for k, v := range t.Meta {
+      meta[k] = v
+    }
+    t.Meta = meta
+  }
+  t.Tags = append([]string(nil), t.Tags...)
+
+  children := make([]repo.Todo, len(t.Children))
+  for i, child := range t.Children {
+    children[i] = nextOccurrence(child, now)
+  }
+  if t.Children != nil {
+    t.Children = children
+  }
+  return t
+}
+
+func parseWeekdays(list string) ([]time.Weekday, error) {
+  seen := map[time.Weekday]bool{}
+  for _, name := range strings.Split(list, ",") {
+    day, ok := weekdayNumber(name)
+    if !ok {
+      return nil, fmt.Errorf("%q is not a weekday", name)
+    }
+    seen[day] = true
+  }
+
+  var days []time.Weekday
+  for day := range seen {
+    days = append(days, day)
+  }
+  // monday first
+  sort.Slice(days, func(i, j int) bool { return (days[i] + 6) % 7 < (days[j] + 6) % 7 })
+  return days, nil
+}
+
+func weekdayNumber(name string) (time.Weekday, bool) {
+  if len(name) < 2 {
+    return 0, false
+  }
+  for i, prefix := range weekdayNames {
+    if strings.HasPrefix(prefix, name) || strings.HasPrefix(name, prefix) {
+      return time.Weekday(i), true
+    }
+  }
+  return 0, false
+}
+
+func parseInterval(s string) (int, byte, error) {
+  if len(s) < 2 {
+    return 0, 0, fmt.Errorf("%q is not an interval like 3d or 2w", s)
+  }
+  unit := s[len(s)-1]
+  n, err := strconv.Atoi(s[:len(s)-1])
+  if err != nil || n < 1 || !strings.ContainsRune("dwmy", rune(unit)) {
+    return 0, 0, fmt.Errorf("%q is not an interval like 3d or 2w", s)
+  }
+  return n, unit, nil
+}
+
+// addMonths moves t by months, keeping its day like "monthly" does rather
+// than overflowing into the month after: Jan 31 plus one month is Feb 28.
+func addMonths(t time.Time, months int) time.Time {
+  first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.Local)
+  return time.Date(first.Year(), first.Month(), clampDay(first, t.Day()), 0, 0, 0, 0, time.Local)
+}
+
+// clampDay is day, or the last day of t's month if that's shorter.
+func clampDay(t time.Time, day int) int {
+  last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.Local).Day()
+  if day > last {
+    return last
+  }
+  return day
+}
+
+// cronMatcher builds a matcher from the day of month, month and weekday
+// fields of a cron line. Like cron, if both days are restricted either one
+// matching is enough.
+func cronMatcher(fields []string) (func(time.Time) bool, error) {
+  if len(fields) != 3 {
+    return nil, errors.New("a cron rule needs day of month, month and weekday")
+  }
+  dom, err := cronField(fields[0], 1, 31, nil)
+  if err != nil {
+    return nil, err
+  }
+  month, err := cronField(fields[1], 1, 12, nil)
+  if err != nil {
+    return nil, err
+  }
+  dow, err := cronField(fields[2], 0, 7, weekdayNumber)
+  if err != nil {
+    return nil, err
+  }
+
+  return func(t time.Time) bool {
+    if !month[int(t.Month())] {
+      return false
+    }
+    weekday := dow[int(t.Weekday())] || (t.Weekday() == time.Sunday && dow[7])
+    switch {
+    case fields[0] != "*" && fields[2] != "*":
+      return dom[t.Day()] || weekday
+    case fields[0] != "*":
+      return dom[t.Day()]
+    }
+    return weekday
+  }, nil
+}
+
+// cronField parses a field like "*", "1-5", "*/2" or "1,15" into the set of
+// values it matches.
+func cronField(field string, min, max int, name func(string) (time.Weekday, bool)) (map[int]bool, error) {
+  value := func(s string) (int, error) {
+    if name != nil {
+      if d, ok := name(s); ok && (s[0] < '0' || s[0] > '9') {
+        return int(d), nil
+      }
+    }
+    n, err := strconv.Atoi(s)
+    if err != nil || n < min || n > max {
+      return 0, fmt.Errorf("%q is out of range in cron field %q", s, field)
+    }
+    return n, nil
+  }
+
+  set := map[int]bool{}
+  for _, part := range strings.Split(field, ",") {
+    stepBy := 1
+    if i := strings.Index(part, "/"); i != -1 {
+      n, err := strconv.Atoi(part[i+1:])
+      if err != nil || n < 1 {
+        return nil, fmt.Errorf("bad step in cron field %q", field)
+      }
+      stepBy, part = n, part[:i]
+    }
+
+    from, to := min, max
+    if part != "*" {
+      bounds := strings.SplitN(part, "-", 2)
+      var err error
+      if from, err = value(bounds[0]); err != nil {
+        return nil, err
+      }
+      to = from
+      if len(bounds) == 2 {
+        if to, err = value(bounds[1]); err != nil {
+          return nil, err
+        }
+      } else if stepBy > 1 {
+        to = max
+      }
+    }
+    for v := from; v <= to; v += stepBy {
+      set[v] = true
+    }
+  }
+  return set, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+func TestParseRepeat(t *testing.T) {
+  tests := []struct {
+    input string
+    want string
+    wantErr bool
+  }{
+    {input: "", want: ""},
+    {input: "none", want: ""},
+    {input: "Daily", want: "daily"},
+    {input: "yearly", want: "every 1y"},
+    {input: "weekdays", want: "weekly mon,tue,wed,thu,fri"},
+    {input: "weekly", want: "weekly"},
+    {input: "weekly thu,mon", want: "weekly mon,thu"},
+    {input: "weekly sun,mon,mon", want: "weekly mon,sun"},
+    {input: "weekly thursday", want: "weekly thu"},
+    {input: "monthly", want: "monthly"},
+    {input: "monthly 15", want: "monthly 15"},
+    {input: "every 3d", want: "every 3d"},
+    {input: "every 2 w", want: "every 2w"},
+    {input: "cron 0 9 1-7 * mon", want: "cron 1-7 * mon"},
+    {input: "cron 1 * *", want: "cron 1 * *"},
+    {input: "weekly someday", wantErr: true},
+    {input: "monthly 32", wantErr: true},
+    {input: "every 0d", wantErr: true},
+    {input: "every 3x", wantErr: true},
+    {input: "cron 1 *", wantErr: true},
+    {input: "cron 32 * *", wantErr: true},
+    {input: "sometimes", wantErr: true},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.input, func(t *testing.T) {
+      got, err := ParseRepeat(tt.input)
+      if (err != nil) != tt.wantErr {
+        t.Fatalf("ParseRepeat(%q) returned error %v", tt.input, err)
+      }
+      if got != tt.want {
+        t.Errorf("ParseRepeat(%q) = %q, want %q", tt.input, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestNextDue(t *testing.T) {
+  // a Sunday
+  now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.Local)
+
+  tests := []struct {
+    rule string
+    due string
+    want string
+    wantErr bool
+  }{
+    {rule: "", want: ""},
+    {rule: "daily", want: "2026-10-19"},
+    {rule: "daily", due: "2026-10-10", want: "2026-10-19"},
+    {rule: "daily", due: "2026-10-25", want: "2026-10-26"},
+    {rule: "weekly", due: "2026-10-15", want: "2026-10-22"},
+    {rule: "weekly", due: "2026-10-01", want: "2026-10-22"},
+    {rule: "weekly mon,thu", want: "2026-10-19"},
+    {rule: "weekly mon,thu", due: "2026-10-19", want: "2026-10-22"},
+    {rule: "monthly", due: "2026-01-31", want: "2026-10-31"},
+    {rule: "monthly 31", due: "2026-11-05", want: "2026-11-30"},
+    {rule: "every 3d", want: "2026-10-21"},
+    {rule: "every 2w", due: "2026-10-10", want: "2026-10-24"},
+    {rule: "every 1m", due: "2026-09-20", want: "2026-10-20"},
+    {rule: "every 1y", due: "2026-02-01", want: "2027-02-01"},
+    // todo.txt stores "monthly" as rec:1m, which reads back as "every 1m"
+    {rule: "every 1m", due: "2026-01-31", want: "2026-10-31"},
+    {rule: "every 2m", due: "2026-08-31", want: "2026-10-31"},
+    {rule: "every 1y", due: "2024-02-29", want: "2027-02-28"},
+    {rule: "cron 1 * *", want: "2026-11-01"},
+    {rule: "cron * * mon-fri", due: "2026-10-23", want: "2026-10-26"},
+    {rule: "cron 29 2 *", want: "2028-02-29"},
+    {rule: "cron 31 2 *", wantErr: true},
+    {rule: "sometimes", wantErr: true},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.rule + " from " + tt.due, func(t *testing.T) {
+      got, err := NextDue(tt.rule, tt.due, now)
+      if (err != nil) != tt.wantErr {
+        t.Fatalf("NextDue(%q, %q) returned error %v", tt.rule, tt.due, err)
+      }
+      if got != tt.want {
+        t.Errorf("NextDue(%q, %q) = %q, want %q", tt.rule, tt.due, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestNextDueAtTheEndOfTheMonth(t *testing.T) {
+  now := time.Date(2026, 1, 31, 15, 0, 0, 0, time.Local)
+  for _, rule := range []string{"monthly", "every 1m"} {
+    got, err := NextDue(rule, "", now)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if got != "2026-02-28" {
+      t.Errorf("NextDue(%q) on January 31 = %q, want 2026-02-28", rule, got)
+    }
+  }
+}
+
+func TestCronMatcher(t *testing.T) {
+  tests := []struct {
+    fields string
+    date string
+    want bool
+  }{
+    {"* * *", "2026-10-18", true},
+    {"1-7 * mon", "2026-10-05", true},
+    // either day field matching is enough
+    {"1-7 * mon", "2026-10-12", true},
+    {"1-7 * mon", "2026-10-03", true},
+    {"1-7 * mon", "2026-10-10", false},
+    {"*/10 * *", "2026-10-11", true},
+    {"*/10 * *", "2026-10-10", false},
+    {"1,15 * *", "2026-10-15", true},
+    {"15 6-8 *", "2026-07-15", true},
+    {"15 6-8 *", "2026-10-15", false},
+    {"* * 7", "2026-10-18", true},
+    {"* * sun", "2026-10-18", true},
+    {"* * mon-fri", "2026-10-18", false},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.fields + " on " + tt.date, func(t *testing.T) {
+      matches, err := cronMatcher(strings.Fields(tt.fields))
+      if err != nil {
+        t.Fatal(err)
+      }
+      date, _ := time.ParseInLocation(repo.DateFormat, tt.date, time.Local)
+      if got := matches(date); got != tt.want {
+        t.Errorf("matches %s = %v, want %v", tt.date, got, tt.want)
+      }
+    })
+  }
+
+  for _, fields := range []string{"0 * *", "* 13 *", "* * funday", "*/0 * *", "* *"} {
+    if _, err := cronMatcher(strings.Fields(fields)); err == nil {
+      t.Errorf("cronMatcher accepted %q", fields)
+    }
+  }
+}
+
+func TestCompletingARecurringItem(t *testing.T) {
+  s, _ := newTestService(t, repo.Todo{Id: "a", Name: "stretch", Due: "2026-10-18", Repeat: "daily"}, item("b"))
+
+  if err := s.ToggleTodo(repo.Todo{Id: "a"}); err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(s.repo.Todos); got != "x:stretch stretch b" {
+    t.Fatalf("todos are %q, want the next occurrence after the completed one", got)
+  }
+  done, next := s.repo.Todos[0], s.repo.Todos[1]
+  if done.Repeat != "" || next.Repeat != "daily" {
+    t.Errorf("the rule is on %q and %q, want it moved to the next occurrence", done.Repeat, next.Repeat)
+  }
+  if next.Id == done.Id || next.Due <= done.Due {
+    t.Errorf("the next occurrence has id %s and due date %s", next.Id, next.Due)
+  }
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// Hit is an item matching a search and the tab it's on.
+type Hit struct {
+  Id string
+  Complete bool
+}
+
+// FuzzyMatch reports whether the letters of query appear in text in order,
+// ignoring case, and returns the positions of the runes of text they
+// matched.
+func FuzzyMatch(query, text string) ([]int, bool) {
+  pattern := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+  if len(pattern) == 0 {
+    return nil, false
+  }
+  var positions []int
+  i := 0
+  for pos, r := range []rune(text) {
+    if i < len(pattern) && unicode.ToLower(r) == pattern[i] {
+      positions = append(positions, pos)
+      i++
+    }
+  }
+  if i < len(pattern) {
+    return nil, false
+  }
+  return positions, true
+}
+
+// Matches reports whether an item matches a search: its name or one of its
+// tags fuzzily, or its notes as they were typed, ignoring case. Notes are
+// long enough to fuzzily match most anything.
+func Matches(item repo.Todo, query string) bool {
+  if _, ok := FuzzyMatch(query, item.Name); ok {
+    return true
+  }
+  for _, tag := range item.Tags {
+    if _, ok := FuzzyMatch(query, repo.TagLabel(tag)); ok {
+      return true
+    }
+  }
+  query = strings.TrimSpace(query)
+  return query != "" && strings.Contains(strings.ToLower(item.Notes), strings.ToLower(query))
+}
+
+// Search lists the items matching query on both tabs, collapsed or not, in
+// the order they're shown: the TODO tab first, then the Complete tab.
+func (s *Service) Search(query string) []Hit {
+  var hits []Hit
+  s.walk(func(item repo.Todo, complete bool) {
+    if Matches(item, query) {
+      hits = append(hits, Hit{Id: item.Id, Complete: complete})
+    }
+  })
+  return hits
+}
+
+// NextHit finds the first item matching query after the one with fromId,
+// or before it when backwards is set, wrapping around at the ends. An
+// unknown fromId searches from the start, or the end.
+func (s *Service) NextHit(query, fromId string, backwards bool) (Hit, bool) {
+  var items []Hit
+  var matches []bool
+  s.walk(func(item repo.Todo, complete bool) {
+    items = append(items, Hit{Id: item.Id, Complete: complete})
+    matches = append(matches, Matches(item, query))
+  })
+
+  step, from := 1, -1
+  if backwards {
+    step, from = -1, len(items)
+  }
+  for i, hit := range items {
+    if hit.Id == fromId {
+      from = i
+    }
+  }
+  for n := 1; n <= len(items); n++ {
+    i := ((from + n*step) % len(items) + len(items)) % len(items)
+    if matches[i] {
+      return items[i], true
+    }
+  }
+  return Hit{}, false
+}
+
+// walk visits every item in the order Search lists them.
+func (s *Service) walk(visit func(item repo.Todo, complete bool)) {
+  var walk func(todos []repo.Todo, complete bool)
+  walk = func(todos []repo.Todo, complete bool) {
+    for _, t := range todos {
+      visit(t, complete)
+      walk(t.Children, complete)
+    }
+  }
+  walk(s.Todos(false), false)
+  walk(s.Todos(true), true)
+}
+
+// Reveal expands every collapsed ancestor of an item so it's shown.
+func (s *Service) Reveal(itemId string) error {
+  before := s.snapshot()
+  var expand func(todos []repo.Todo) (bool, bool)
+  expand = func(todos []repo.Todo) (found, changed bool) {
+    for i := range todos {
+      if todos[i].Id == itemId {
+        return true, false
+      }
+      if found, changed := expand(todos[i].Children); found {
+        changed = changed || !todos[i].Expanded
+        todos[i].Expanded = true
+        return true, changed
+      }
+    }
+    return false, false
+  }
+
+  found, changed := expand(s.repo.Todos)
+  if !found || !changed {
+    return nil
+  }
+  return s.commit("reveal '" + s.find(itemId).Name + "'", before)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+  tests := []struct {
+    query string
+    text string
+    want []int
+    wantOk bool
+  }{
+    {"", "abc", nil, false},
+    {"  ", "abc", nil, false},
+    {"abc", "abc", []int{0, 1, 2}, true},
+    {"ac", "abc", []int{0, 2}, true},
+    {"AC", "abc", []int{0, 2}, true},
+    {"ac", "ABC", []int{0, 2}, true},
+    {"w m", "write migration", []int{0, 6}, true},
+    {"milk", "buy milk", []int{4, 5, 6, 7}, true},
+    {"aa", "banana", []int{1, 3}, true},
+    {"é", "café", []int{3}, true},
+    {"ca", "abc", nil, false},
+    {"abcd", "abc", nil, false},
+    {"xyz", "abc", nil, false},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.query + " in " + tt.text, func(t *testing.T) {
+      got, ok := FuzzyMatch(tt.query, tt.text)
+      if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+        t.Errorf("FuzzyMatch(%q, %q) = %v, %v, want %v, %v", tt.query, tt.text, got, ok, tt.want, tt.wantOk)
+      }
+    })
+  }
+}
+
+func TestMatches(t *testing.T) {
+  todo := repo.Todo{Name: "write migration", Tags: []string{"backend", "@sam"}, Notes: "See the Schema doc"}
+
+  tests := []struct {
+    query string
+    want bool
+  }{
+    {"wrmig", true},
+    {"#bkend", true},
+    {"@sam", true},
+    {"schema doc", true},
+    {"sch doc", false},
+    {"frontend", false},
+    {"", false},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.query, func(t *testing.T) {
+      if got := Matches(todo, tt.query); got != tt.want {
+        t.Errorf("Matches(%q) = %v, want %v", tt.query, got, tt.want)
+      }
+    })
+  }
+}
@@ -1,19 +1,96 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/jquag/tui-do/repo"
 )
 
 type Service struct {
   repo *repo.Repo
+  undo []change
+  redo []change
+  // lists of a workspace, repo is the one of the active list
+  lists []*List
+  active int
+  watch chan struct{}
+  // generation counts the changes to the todos, so views can be cached
+  generation int
 }
 
 func NewService(r *repo.Repo) *Service {
   return &Service{repo: r}
 }
 
+// Watch reports when the todos were changed outside of this process. In a
+// workspace that is any of its lists.
+func (s *Service) Watch() <-chan struct{} {
+  if len(s.lists) == 0 {
+    return s.repo.Watch()
+  }
+  if s.watch == nil {
+    s.watch = make(chan struct{}, 1)
+    for _, l := range s.lists {
+      if changes := l.Repo.Watch(); changes != nil {
+        go forward(changes, s.watch)
+      }
+    }
+  }
+  return s.watch
+}
+
+func forward(from <-chan struct{}, to chan struct{}) {
+  for range from {
+    select {
+    case to <- struct{}{}:
+    default:
+    }
+  }
+}
+
+// Reload picks up outside changes to the todos, or to every list of a
+// workspace, merging them with unsaved local edits.
+// Changes made before can't be undone afterwards, undoing them would throw
+// away what was changed outside. A list that reloads the same as it was
+// keeps its undo history.
+func (s *Service) Reload() (merged bool, err error) {
+  for _, l := range s.lists {
+    if l.Repo == s.repo {
+      continue
+    }
+    before := repo.Clone(l.Repo.Todos)
+    if _, err := l.Repo.Reload(); err != nil {
+      return false, fmt.Errorf("%s: %w", l.Name, err)
+    }
+    if !repo.Equal(before, l.Repo.Todos) {
+      l.undo, l.redo = nil, nil
+    }
+  }
+
+  before := s.snapshot()
+  merged, err = s.repo.Reload()
+  if !repo.Equal(before, s.repo.Todos) {
+    s.generation++
+    s.undo, s.redo = nil, nil
+  }
+  return merged, err
+}
+
+// Close releases the stores of the todos, or of every list of a workspace.
+func (s *Service) Close() error {
+  if len(s.lists) == 0 {
+    return s.repo.Close()
+  }
+  var errs []error
+  for _, l := range s.lists {
+    errs = append(errs, l.Repo.Close())
+  }
+  return errors.Join(errs...)
+}
+
 func (s *Service) Todos(completeFilter bool) []repo.Todo {
   var filtered []repo.Todo
 
@@ -39,11 +116,9 @@ func (s *Service) isAllDone(item repo.Todo) bool {
   return true
 }
 
-func (s *Service) AddTodo(afterItem *repo.Todo, name string) {
-  t := repo.Todo{
-    Id: uuid.New().String(),
-    Name: name,
-  }
+func (s *Service) AddTodo(afterItem *repo.Todo, name string) error {
+  before := s.snapshot()
+  t := newTodo(name, time.Now())
 
   if afterItem == nil {
     s.repo.Todos = append([]repo.Todo{t}, s.repo.Todos...)
@@ -83,22 +158,21 @@ func (s *Service) AddTodo(afterItem *repo.Todo, name string) {
     }
   }
 
-  s.repo.Persist()
+  return s.commit("add '" + t.Name + "'", before)
 }
 
-func (s *Service) AddTodoAsChild(parent *repo.Todo, name string) {
-  t := repo.Todo{
-    Id: uuid.New().String(),
-    Name: name,
-  }
+func (s *Service) AddTodoAsChild(parent *repo.Todo, name string) error {
+  before := s.snapshot()
+  t := newTodo(name, time.Now())
 
   _, item := s.findItemAndParent(parent.Id, nil)
   item.Children = append([]repo.Todo{t}, item.Children...)
   item.Expanded = true
-  s.repo.Persist()
+  return s.commit("add '" + t.Name + "'", before)
 }
 
-func (s *Service) CollapseAll(completed bool) {
+func (s *Service) CollapseAll(completed bool) error {
+  before := s.snapshot()
   for i, item := range s.repo.Todos {
     if s.isAllDone(item) == completed {
       (&s.repo.Todos[i]).Expanded = false
@@ -107,7 +181,7 @@ func (s *Service) CollapseAll(completed bool) {
       }
     }
   }
-  s.repo.Persist()
+  return s.commit("collapse all", before)
 }
 
 func (s *Service) collapseAllFromSlice(todos []repo.Todo) {
@@ -137,15 +211,57 @@ func (s *Service) findItemAndParent(itemId string, currentParent *repo.Todo) (*r
   return currentParent, nil
 }
 
-func (s *Service) ToggleTodo(item repo.Todo) {
-  s.toggleTodoFromSlice(item, s.repo.Todos)
+// siblings is the list holding the children of parent, or the top-level
+// list for a nil parent.
+func (s *Service) siblings(parent *repo.Todo) *[]repo.Todo {
+  if parent == nil {
+    return &s.repo.Todos
+  }
+  return &parent.Children
+}
+
+// insertAfter puts todos into the children of parent, right after the item
+// with afterId or at the end if there is no such item.
+func (s *Service) insertAfter(parent *repo.Todo, afterId string, todos ...repo.Todo) {
+  list := s.siblings(parent)
+  index := len(*list)
+  for i := range *list {
+    if (*list)[i].Id == afterId {
+      index = i + 1
+      break
+    }
+  }
+  rest := append(todos, (*list)[index:]...)
+  *list = append((*list)[:index], rest...)
+}
+
+func (s *Service) find(itemId string) *repo.Todo {
+  _, item := s.findItemAndParent(itemId, nil)
+  return item
+}
+
+// ToggleTodo completes or reopens an item. Completing a recurring item adds
+// its next occurrence.
+func (s *Service) ToggleTodo(item repo.Todo) error {
+  before := s.snapshot()
+  if !s.toggleTodoFromSlice(item, s.repo.Todos) {
+    return nil
+  }
+  label := "complete '" + item.Name + "'"
+  if item.Done {
+    label = "reopen '" + item.Name + "'"
+  }
+  repeatErr := s.repeat(item.Id, time.Now())
+  if err := s.commit(label, before); err != nil {
+    return err
+  }
+  return repeatErr
 }
 
 func (s *Service) toggleTodoFromSlice(item repo.Todo, scope []repo.Todo) (bool) {
   for i, t := range scope {
     if t.Id == item.Id {
-      scope[i].Done = !t.Done
-      s.repo.Persist()
+      setDone(&scope[i], !t.Done, time.Now())
       return true
     } else {
       done := s.toggleTodoFromSlice(item, t.Children)
@@ -157,15 +273,22 @@ func (s *Service) toggleTodoFromSlice(item repo.Todo, scope []repo.Todo) (bool)
   return false
 }
 
-func (s *Service) ToggleExpanded(item repo.Todo) {
-  s.toggleExpandedFromSlice(item, s.repo.Todos)
+func (s *Service) ToggleExpanded(item repo.Todo) error {
+  before := s.snapshot()
+  if s.toggleExpandedFromSlice(item, s.repo.Todos) {
+    label := "expand '" + item.Name + "'"
+    if item.Expanded {
+      label = "collapse '" + item.Name + "'"
+    }
+    return s.commit(label, before)
+  }
+  return nil
 }
 
 func (s *Service) toggleExpandedFromSlice(item repo.Todo, scope []repo.Todo) (bool) {
   for i, t := range scope {
     if t.Id == item.Id {
       scope[i].Expanded = !t.Expanded
-      s.repo.Persist()
       return true
     } else {
       done := s.toggleExpandedFromSlice(item, t.Children)
@@ -177,15 +300,20 @@ func (s *Service) toggleExpandedFromSlice(item repo.Todo, scope []repo.Todo) (bo
   return false
 }
 
-func (s *Service) ChangeTodo(item repo.Todo, name string) {
-  s.changeTodoFromSlice(item, name, s.repo.Todos)
+// ChangeTodo renames an item. Any #tags and @mentions in name replace the
+// item's tags.
+func (s *Service) ChangeTodo(item repo.Todo, name string) error {
+  before := s.snapshot()
+  if s.changeTodoFromSlice(item, name, s.repo.Todos) {
+    return s.commit("rename '" + item.Name + "'", before)
+  }
+  return nil
 }
 
 func (s *Service) changeTodoFromSlice(item repo.Todo, name string, scope []repo.Todo) (bool) {
   for i, t := range scope {
     if t.Id == item.Id {
-      scope[i].Name = name
-      s.repo.Persist()
+      rename(&scope[i], name, time.Now())
       return true
     } else {
       done := s.changeTodoFromSlice(item, name, t.Children)
@@ -197,8 +325,23 @@ func (s *Service) changeTodoFromSlice(item repo.Todo, name string, scope []repo.
   return false
 }
 
-func (s *Service) DeleteTodo(item repo.Todo) {
-  s.deleteTodoFromParent(item, nil)
+// SetNotes replaces the notes of an item.
+func (s *Service) SetNotes(item repo.Todo, notes string) error {
+  before := s.snapshot()
+  if t := s.find(item.Id); t != nil {
+    t.Notes = strings.TrimRight(notes, " \t\n")
+    touch(t, time.Now())
+    return s.commit("edit notes of '" + t.Name + "'", before)
+  }
+  return nil
+}
+
+func (s *Service) DeleteTodo(item repo.Todo) error {
+  before := s.snapshot()
+  if s.deleteTodoFromParent(item, nil) {
+    return s.commit("delete '" + item.Name + "'", before)
+  }
+  return nil
 }
 
 func (s *Service) deleteTodoFromParent(item repo.Todo, parent *repo.Todo) (bool) {
@@ -227,9 +370,31 @@ func (s *Service) deleteTodoFromParent(item repo.Todo, parent *repo.Todo) (bool)
     } else {
       parent.Children = append(parent.Children[:indexToDelete], parent.Children[indexToDelete+1:]...)
     }
-    s.repo.Persist()
     return true
   }
 
   return false
 }
+
+// Backups lists the automatic snapshots of the todos, newest first.
+func (s *Service) Backups() ([]repo.Backup, error) {
+  return s.repo.Backups()
+}
+
+// BackupDiff compares a snapshot with the current todos.
+func (s *Service) BackupDiff(b repo.Backup) ([]repo.DiffLine, error) {
+  todos, err := repo.LoadBackup(b.Path)
+  if err != nil {
+    return nil, err
+  }
+  return repo.Diff(todos, s.repo.Todos), nil
+}
+
+func (s *Service) RestoreBackup(b repo.Backup) error {
+  before := s.snapshot()
+  if err := s.repo.Restore(b); err != nil {
+    return err
+  }
+  s.record("restore backup " + b.Name(), before)
+  return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// newTestService creates a Service on a MemoryStore holding todos.
+func newTestService(t *testing.T, todos ...repo.Todo) (*Service, *repo.MemoryStore) {
+  t.Helper()
+  store := repo.NewMemoryStore(todos...)
+  r, err := repo.New(store)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return NewService(r), store
+}
+
+// item is a todo named after its id.
+func item(id string, children ...repo.Todo) repo.Todo {
+  return repo.Todo{Id: id, Name: id, Children: children}
+}
+
+// outline describes todos in one line like "a(b x:c) d", where c is done.
+func outline(todos []repo.Todo) string {
+  var parts []string
+  for _, t := range todos {
+    part := repo.JoinTags(t.Name, t.Tags)
+    if t.Done {
+      part = "x:" + part
+    }
+    if len(t.Children) > 0 {
+      part += "(" + outline(t.Children) + ")"
+    }
+    parts = append(parts, part)
+  }
+  return strings.Join(parts, " ")
+}
+
+func TestMutations(t *testing.T) {
+  tests := []struct {
+    name string
+    mutate func(s *Service) error
+    want string
+  }{
+    {"add at the top", func(s *Service) error { return s.AddTodo(nil, "new") }, "new a(b c) d"},
+    {"add after an item", func(s *Service) error { return s.AddTodo(&repo.Todo{Id: "a"}, "new") }, "a(b c) new d"},
+    {"add after the last item", func(s *Service) error { return s.AddTodo(&repo.Todo{Id: "d"}, "new") }, "a(b c) d new"},
+    {"add after a child", func(s *Service) error { return s.AddTodo(&repo.Todo{Id: "b"}, "new") }, "a(b new c) d"},
+    {"add a child", func(s *Service) error { return s.AddTodoAsChild(&repo.Todo{Id: "a"}, "new") }, "a(new b c) d"},
+    {"add with tags", func(s *Service) error { return s.AddTodo(nil, "new #x @bob") }, "new #x @bob a(b c) d"},
+    {"complete", func(s *Service) error { return s.ToggleTodo(repo.Todo{Id: "c"}) }, "a(b x:c) d"},
+    {"rename", func(s *Service) error { return s.ChangeTodo(repo.Todo{Id: "d"}, "e #x") }, "a(b c) e #x"},
+    {"delete a child", func(s *Service) error { return s.DeleteTodo(repo.Todo{Id: "b"}) }, "a(c) d"},
+    {"delete a parent", func(s *Service) error { return s.DeleteTodo(repo.Todo{Id: "a"}) }, "d"},
+    {"delete a missing item", func(s *Service) error { return s.DeleteTodo(repo.Todo{Id: "z"}) }, "a(b c) d"},
+    {"rename a missing item", func(s *Service) error { return s.ChangeTodo(repo.Todo{Id: "z"}, "e") }, "a(b c) d"},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s, store := newTestService(t, item("a", item("b"), item("c")), item("d"))
+      if err := tt.mutate(s); err != nil {
+        t.Fatal(err)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      saved, _ := store.Load()
+      if got := outline(saved); got != tt.want {
+        t.Errorf("saved todos are %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestToggleTodoRecordsCompletion(t *testing.T) {
+  s, _ := newTestService(t, item("a"))
+
+  if err := s.ToggleTodo(repo.Todo{Id: "a"}); err != nil {
+    t.Fatal(err)
+  }
+  a := s.find("a")
+  if !a.Done || a.CompletedAt == "" {
+    t.Fatalf("completed item has Done %v and CompletedAt %q", a.Done, a.CompletedAt)
+  }
+  if len(a.History) != 1 || a.History[0].Action != repo.EventCompleted {
+    t.Errorf("history is %+v, want a completed event", a.History)
+  }
+
+  if err := s.ToggleTodo(*a); err != nil {
+    t.Fatal(err)
+  }
+  if a := s.find("a"); a.Done || a.CompletedAt != "" {
+    t.Errorf("reopened item has Done %v and CompletedAt %q", a.Done, a.CompletedAt)
+  }
+}
+
+func TestSetNotes(t *testing.T) {
+  s, store := newTestService(t, item("a"))
+
+  if err := s.SetNotes(repo.Todo{Id: "a"}, "some notes\n\n"); err != nil {
+    t.Fatal(err)
+  }
+  saved, _ := store.Load()
+  if saved[0].Notes != "some notes" {
+    t.Errorf("notes are %q, want trailing blank lines trimmed", saved[0].Notes)
+  }
+  if saved[0].UpdatedAt == "" {
+    t.Error("editing notes didn't set UpdatedAt")
+  }
+}
+
+func TestExpandAndCollapse(t *testing.T) {
+  s, _ := newTestService(t, item("a", item("b", item("c"))), item("d"))
+
+  if err := s.ToggleExpanded(repo.Todo{Id: "b"}); err != nil {
+    t.Fatal(err)
+  }
+  if !s.find("b").Expanded {
+    t.Fatal("b wasn't expanded")
+  }
+  s.find("a").Expanded = true
+
+  if err := s.CollapseAll(false); err != nil {
+    t.Fatal(err)
+  }
+  for _, id := range []string{"a", "b", "c"} {
+    if s.find(id).Expanded {
+      t.Errorf("%s is still expanded after collapsing all", id)
+    }
+  }
+}
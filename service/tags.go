@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// AllTags lists every tag in use, sorted.
+func (s *Service) AllTags() []string {
+  seen := map[string]bool{}
+  var walk func(todos []repo.Todo)
+  walk = func(todos []repo.Todo) {
+    for _, t := range todos {
+      for _, tag := range t.Tags {
+        seen[strings.ToLower(tag)] = true
+      }
+      walk(t.Children)
+    }
+  }
+  walk(s.repo.Todos)
+
+  tags := make([]string, 0, len(seen))
+  for tag := range seen {
+    tags = append(tags, tag)
+  }
+  sort.Strings(tags)
+  return tags
+}
+
+// FilterByTags keeps the items carrying all of tags along with their
+// ancestors, which are expanded so the matches show. The todos are not
+// changed, the result is a pruned copy.
+func FilterByTags(todos []repo.Todo, tags []string) []repo.Todo {
+  return prune(todos, func(t repo.Todo) bool {
+    for _, tag := range tags {
+      if !t.HasTag(tag) {
+        return false
+      }
+    }
+    return true
+  })
+}
+
+// prune keeps the items matching keep and the ancestors of those items.
+// Ancestors that don't match themselves are expanded to show the matches,
+// the others keep their own expanded state.
+func prune(todos []repo.Todo, keep func(repo.Todo) bool) []repo.Todo {
+  var result []repo.Todo
+  for _, t := range todos {
+    children := prune(t.Children, keep)
+    matches := keep(t)
+    if len(children) == 0 && !matches {
+      continue
+    }
+    t.Children = children
+    if !matches {
+      t.Expanded = true
+    }
+    result = append(result, t)
+  }
+  return result
+}
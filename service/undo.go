@@ -0,0 +1,66 @@
+package service
+
+import "github.com/jquag/tui-do/repo"
+
+// how many changes can be undone
+const undoLimit = 100
+
+// change is the state of the todos before or after a mutation, along with
+// what the mutation did, like "delete 'write migration'".
+type change struct {
+  label string
+  todos []repo.Todo
+  // moved is set when an item went to another list
+  moved *listMove
+}
+
+// snapshot copies the todos so a mutation can be undone later.
+func (s *Service) snapshot() []repo.Todo {
+  return repo.Clone(s.repo.Todos)
+}
+
+// commit records a mutation for undo and persists it. before is the
+// snapshot taken before the todos were changed.
+func (s *Service) commit(label string, before []repo.Todo) error {
+  s.record(label, before)
+  return s.repo.Persist()
+}
+
+// record makes a mutation that has already been persisted undoable.
+func (s *Service) record(label string, before []repo.Todo) {
+  s.generation++
+  s.undo = append(s.undo, change{label: label, todos: before})
+  if len(s.undo) > undoLimit {
+    s.undo = s.undo[len(s.undo)-undoLimit:]
+  }
+  s.redo = nil
+}
+
+// Undo reverts the last mutation and returns what it was, or "" if there is
+// nothing to undo.
+func (s *Service) Undo() (string, error) {
+  return s.travel(&s.undo, &s.redo)
+}
+
+// Redo applies the last undone mutation again.
+func (s *Service) Redo() (string, error) {
+  return s.travel(&s.redo, &s.undo)
+}
+
+func (s *Service) travel(from, to *[]change) (string, error) {
+  if len(*from) == 0 {
+    return "", nil
+  }
+  c := (*from)[len(*from)-1]
+  if c.moved != nil {
+    // the other list goes first, so if it fails nothing has changed here
+    if err := c.moved.apply(); err != nil {
+      return c.label, err
+    }
+  }
+  s.generation++
+  *from = (*from)[:len(*from)-1]
+  *to = append(*to, change{label: c.label, todos: s.snapshot(), moved: c.moved})
+  s.repo.Todos = c.todos
+  return c.label, s.repo.Persist()
+}
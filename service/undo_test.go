@@ -0,0 +1,229 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+func TestUndoRedo(t *testing.T) {
+  tests := []struct {
+    name string
+    mutate func(s *Service) error
+    steps []string
+    want string
+    wantLabel string
+  }{
+    {
+      name: "nothing to undo",
+      mutate: func(s *Service) error { return nil },
+      steps: []string{"undo"},
+      want: "a b",
+    },
+    {
+      name: "nothing to redo",
+      mutate: func(s *Service) error { return s.DeleteTodo(repo.Todo{Id: "b", Name: "b"}) },
+      steps: []string{"redo"},
+      want: "a",
+    },
+    {
+      name: "undo an add",
+      mutate: func(s *Service) error { return s.AddTodo(nil, "new") },
+      steps: []string{"undo"},
+      want: "a b",
+      wantLabel: "add 'new'",
+    },
+    {
+      name: "undo the last of two",
+      mutate: func(s *Service) error {
+        if err := s.ToggleTodo(repo.Todo{Id: "a", Name: "a"}); err != nil {
+          return err
+        }
+        return s.DeleteTodo(repo.Todo{Id: "b", Name: "b"})
+      },
+      steps: []string{"undo"},
+      want: "x:a b",
+      wantLabel: "delete 'b'",
+    },
+    {
+      name: "undo both",
+      mutate: func(s *Service) error {
+        if err := s.ToggleTodo(repo.Todo{Id: "a", Name: "a"}); err != nil {
+          return err
+        }
+        return s.DeleteTodo(repo.Todo{Id: "b", Name: "b"})
+      },
+      steps: []string{"undo", "undo"},
+      want: "a b",
+      wantLabel: "complete 'a'",
+    },
+    {
+      name: "undo past the first change",
+      mutate: func(s *Service) error { return s.AddTodo(nil, "new") },
+      steps: []string{"undo", "undo"},
+      want: "a b",
+    },
+    {
+      name: "redo",
+      mutate: func(s *Service) error { return s.ChangeTodo(repo.Todo{Id: "a", Name: "a"}, "c") },
+      steps: []string{"undo", "redo"},
+      want: "c b",
+      wantLabel: "rename 'a'",
+    },
+    {
+      name: "undo a redo",
+      mutate: func(s *Service) error { return s.ChangeTodo(repo.Todo{Id: "a", Name: "a"}, "c") },
+      steps: []string{"undo", "redo", "undo"},
+      want: "a b",
+      wantLabel: "rename 'a'",
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      s, store := newTestService(t, item("a"), item("b"))
+      if err := tt.mutate(s); err != nil {
+        t.Fatal(err)
+      }
+      var label string
+      for _, step := range tt.steps {
+        var err error
+        if step == "undo" {
+          label, err = s.Undo()
+        } else {
+          label, err = s.Redo()
+        }
+        if err != nil {
+          t.Fatal(err)
+        }
+      }
+      if label != tt.wantLabel {
+        t.Errorf("the last step was %q, want %q", label, tt.wantLabel)
+      }
+      if got := outline(s.repo.Todos); got != tt.want {
+        t.Errorf("todos are %q, want %q", got, tt.want)
+      }
+      saved, _ := store.Load()
+      if got := outline(saved); got != tt.want {
+        t.Errorf("saved todos are %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestNewChangeClearsRedo(t *testing.T) {
+  s, _ := newTestService(t, item("a"))
+  if err := s.AddTodo(nil, "b"); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := s.Undo(); err != nil {
+    t.Fatal(err)
+  }
+  if err := s.AddTodo(nil, "c"); err != nil {
+    t.Fatal(err)
+  }
+  if label, _ := s.Redo(); label != "" {
+    t.Errorf("redid %q after a new change", label)
+  }
+}
+
+func TestUndoLimit(t *testing.T) {
+  s, _ := newTestService(t)
+  for i := 0; i < undoLimit + 5; i++ {
+    if err := s.AddTodo(nil, fmt.Sprint(i)); err != nil {
+      t.Fatal(err)
+    }
+  }
+  undone := 0
+  for {
+    label, err := s.Undo()
+    if err != nil {
+      t.Fatal(err)
+    }
+    if label == "" {
+      break
+    }
+    undone++
+  }
+  if undone != undoLimit || len(s.repo.Todos) != 5 {
+    t.Errorf("undid %d changes leaving %d items, want %d leaving 5", undone, len(s.repo.Todos), undoLimit)
+  }
+}
+
+func TestUndoMoveToList(t *testing.T) {
+  newRepo := func(todos ...repo.Todo) *repo.Repo {
+    r, err := repo.New(repo.NewMemoryStore(todos...))
+    if err != nil {
+      t.Fatal(err)
+    }
+    return r
+  }
+  home, work := newRepo(item("a"), item("b")), newRepo(item("c"))
+  s := NewWorkspace([]List{{Name: "home", Repo: home}, {Name: "work", Repo: work}})
+
+  if err := s.MoveToList(repo.Todo{Id: "a", Name: "a"}, "work"); err != nil {
+    t.Fatal(err)
+  }
+  if outline(home.Todos) != "b" || outline(work.Todos) != "c a" {
+    t.Fatalf("after the move home is %q and work %q", outline(home.Todos), outline(work.Todos))
+  }
+
+  // the moved item is nested somewhere else in the other list meanwhile
+  if err := s.SwitchList("work"); err != nil {
+    t.Fatal(err)
+  }
+  if err := s.Indent(repo.Todo{Id: "a", Name: "a"}); err != nil {
+    t.Fatal(err)
+  }
+  if got := outline(work.Todos); got != "c(a)" {
+    t.Fatalf("after indenting work is %q", got)
+  }
+  if err := s.SwitchList("home"); err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err := s.Undo(); err != nil {
+    t.Fatal(err)
+  }
+  if outline(home.Todos) != "a b" || outline(work.Todos) != "c" {
+    t.Errorf("after undo home is %q and work %q", outline(home.Todos), outline(work.Todos))
+  }
+
+  if _, err := s.Redo(); err != nil {
+    t.Fatal(err)
+  }
+  if outline(home.Todos) != "b" || outline(work.Todos) != "c a" {
+    t.Errorf("after redo home is %q and work %q", outline(home.Todos), outline(work.Todos))
+  }
+}
+
+func TestReloadKeepsUndo(t *testing.T) {
+  s, store := newTestService(t, item("a"))
+  if err := s.AddTodo(nil, "b"); err != nil {
+    t.Fatal(err)
+  }
+
+  // our own save coming back
+  if _, err := s.Reload(); err != nil {
+    t.Fatal(err)
+  }
+  if label, _ := s.Undo(); label != "add 'b'" {
+    t.Fatalf("undid %q after reloading our own save, want %q", label, "add 'b'")
+  }
+
+  if err := s.AddTodo(nil, "c"); err != nil {
+    t.Fatal(err)
+  }
+  saved, _ := store.Load()
+  store.Save(append(saved, item("outside")))
+  if _, err := s.Reload(); err != nil {
+    t.Fatal(err)
+  }
+  if label, _ := s.Undo(); label != "" {
+    t.Errorf("undid %q after an outside change", label)
+  }
+  if got := outline(s.repo.Todos); got != "c a outside" {
+    t.Errorf("todos are %q, want %q", got, "c a outside")
+  }
+}
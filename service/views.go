@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// The orders a View can sort by. The dates sort newest first.
+var viewSorts = []string{"priority", "due", "name", "created", "completed"}
+
+// The ways a View can group its top-level items.
+var viewGroups = []string{"priority", "due", "tag", "completed"}
+
+// Config is the config file, like:
+//
+//   {
+//     "Views": [
+//       {"Name": "Backend", "Query": "tag:backend and not done", "Sort": "due"},
+//       {"Name": "This week", "Query": "due<+7d", "Group": "due"}
+//     ]
+//   }
+type Config struct {
+  Views []View
+}
+
+// View is a saved filter of the todos, shown as a tab of its own.
+type View struct {
+  Name string
+  // Query picks the items shown, see Query. All of them without one.
+  Query string `json:",omitempty"`
+  // Sort is one of viewSorts, or "" to keep the list order
+  Sort string `json:",omitempty"`
+  // Group is one of viewGroups, or "" for no grouping
+  Group string `json:",omitempty"`
+  query *Query
+  cache *viewCache
+}
+
+// viewCache is what ViewTodos last returned for a view.
+type viewCache struct {
+  svc *Service
+  generation int
+  day string
+  todos []repo.Todo
+}
+
+// LoadConfig reads the config file at path. A missing file is the same as
+// an empty one.
+func LoadConfig(path string) (Config, error) {
+  var config Config
+  content, err := os.ReadFile(path)
+  if errors.Is(err, fs.ErrNotExist) {
+    return config, nil
+  }
+  if err != nil {
+    return config, err
+  }
+  if err := json.Unmarshal(content, &config); err != nil {
+    return Config{}, fmt.Errorf("%s: %w", path, err)
+  }
+  for i := range config.Views {
+    if err := config.Views[i].compile(); err != nil {
+      return Config{}, fmt.Errorf("%s: view %q: %w", path, config.Views[i].Name, err)
+    }
+  }
+  return config, nil
+}
+
+func (v *View) compile() error {
+  if v.Name == "" {
+    return errors.New("a view needs a name")
+  }
+  v.cache = &viewCache{}
+  if v.Query != "" {
+    q, err := ParseQuery(v.Query)
+    if err != nil {
+      return err
+    }
+    v.query = q
+  }
+  if v.Sort != "" && !oneOf(v.Sort, viewSorts) {
+    return fmt.Errorf("can't sort by %q, try %s", v.Sort, strings.Join(viewSorts, ", "))
+  }
+  if v.Group != "" && !oneOf(v.Group, viewGroups) {
+    return fmt.Errorf("can't group by %q, try %s", v.Group, strings.Join(viewGroups, ", "))
+  }
+  return nil
+}
+
+func oneOf(s string, options []string) bool {
+  for _, option := range options {
+    if s == option {
+      return true
+    }
+  }
+  return false
+}
+
+// ViewTodos are the items a view shows: the ones matching its query on
+// either tab, along with their ancestors, sorted and grouped. They are
+// only worked out again once the todos changed or the day did.
+func (s *Service) ViewTodos(v View, now time.Time) []repo.Todo {
+  day := now.Format(repo.DateFormat)
+  if c := v.cache; c != nil && c.svc == s && c.generation == s.generation && c.day == day {
+    return c.todos
+  }
+
+  todos := repo.Clone(s.repo.Todos)
+  if v.query != nil {
+    todos = FilterByQuery(todos, v.query, now)
+  }
+  if v.Sort != "" {
+    s.sortTodos(todos, v.Sort)
+  }
+  if v.Group != "" {
+    sort.SliceStable(todos, func(i, j int) bool {
+      _, a := s.groupOf(todos[i], v.Group, now)
+      _, b := s.groupOf(todos[j], v.Group, now)
+      return a < b
+    })
+  }
+  if v.cache != nil {
+    *v.cache = viewCache{svc: s, generation: s.generation, day: day, todos: todos}
+  }
+  return todos
+}
+
+// sortTodos sorts todos and all their children in place.
+func (s *Service) sortTodos(todos []repo.Todo, order string) {
+  key := func(t repo.Todo) string {
+    switch order {
+    case "priority":
+      return string(rune(priorityRank(t)))
+    case "due":
+      if due := s.DueDate(t); due != "" {
+        return due
+      }
+      return "~"
+    case "name":
+      return strings.ToLower(t.Name)
+    }
+    return ""
+  }
+  sort.SliceStable(todos, func(i, j int) bool {
+    switch order {
+    case "created":
+      a, _ := repo.ParseTimestamp(todos[i].CreatedAt)
+      b, _ := repo.ParseTimestamp(todos[j].CreatedAt)
+      return a.After(b)
+    case "completed":
+      return s.CompletedAt(todos[i]).After(s.CompletedAt(todos[j]))
+    }
+    return key(todos[i]) < key(todos[j])
+  })
+  for i := range todos {
+    s.sortTodos(todos[i].Children, order)
+  }
+}
+
+// GroupOf is the header a top-level item is shown under in a view grouped
+// by group.
+func (s *Service) GroupOf(item repo.Todo, group string, now time.Time) string {
+  label, _ := s.groupOf(item, group, now)
+  return label
+}
+
+// groupOf returns the group of an item along with a key that sorts the
+// groups in the order they're shown.
+func (s *Service) groupOf(item repo.Todo, group string, now time.Time) (label string, rank string) {
+  switch group {
+  case "priority":
+    if item.Priority == "" {
+      return "No priority", "~"
+    }
+    return "Priority " + item.Priority, item.Priority
+
+  case "due":
+    due := s.DueDate(item)
+    today := now.Format(repo.DateFormat)
+    switch {
+    case due == "":
+      return "No due date", "4"
+    case due < today:
+      return "Overdue", "0"
+    case due == today:
+      return "Today", "1"
+    case due < now.AddDate(0, 0, 7).Format(repo.DateFormat):
+      return "Next 7 days", "2"
+    }
+    return "Later", "3"
+
+  case "tag":
+    if len(item.Tags) == 0 {
+      return "Untagged", "2"
+    }
+    return repo.TagLabel(item.Tags[0]), "1" + strings.ToLower(item.Tags[0])
+
+  case "completed":
+    if !s.isAllDone(item) {
+      return "Open", "0"
+    }
+    switch label := s.CompletionGroup(item, now); label {
+    case CompletedToday:
+      return label, "1"
+    case CompletedThisWeek:
+      return label, "2"
+    default:
+      return label, "3"
+    }
+  }
+  return "", ""
+}
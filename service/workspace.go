@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jquag/tui-do/repo"
+)
+
+// List is one of the named lists of a workspace.
+type List struct {
+  Name string
+  Repo *repo.Repo
+  // the undo history of the list while another one is active
+  undo []change
+  redo []change
+}
+
+// listMove is an item moved to another list. Undoing the move on the list
+// it came from takes it out of the other list again.
+type listMove struct {
+  list *List
+  item repo.Todo
+}
+
+// NewWorkspace creates a Service for several named lists, the first of
+// them active.
+func NewWorkspace(lists []List) *Service {
+  s := &Service{}
+  for i := range lists {
+    list := lists[i]
+    s.lists = append(s.lists, &list)
+  }
+  s.repo = s.lists[0].Repo
+  return s
+}
+
+// Lists are the names of the lists in the workspace, empty for a single
+// todo file.
+func (s *Service) Lists() []string {
+  var names []string
+  for _, l := range s.lists {
+    names = append(names, l.Name)
+  }
+  return names
+}
+
+// ActiveList is the name of the list being worked on.
+func (s *Service) ActiveList() string {
+  if len(s.lists) == 0 {
+    return ""
+  }
+  return s.lists[s.active].Name
+}
+
+// SwitchList makes another list the active one. Each list keeps its own
+// undo history.
+func (s *Service) SwitchList(name string) error {
+  i := s.indexOfList(name)
+  if i == -1 {
+    return fmt.Errorf("no list named %q", name)
+  }
+  s.lists[s.active].undo, s.lists[s.active].redo = s.undo, s.redo
+  s.generation++
+  s.active = i
+  s.repo = s.lists[i].Repo
+  s.undo, s.redo = s.lists[i].undo, s.lists[i].redo
+  return nil
+}
+
+func (s *Service) indexOfList(name string) int {
+  for i, l := range s.lists {
+    if l.Name == name {
+      return i
+    }
+  }
+  return -1
+}
+
+// MoveToList moves an item and everything under it to the end of another
+// list. The other list forgets its undo history, as with Reload.
+func (s *Service) MoveToList(item repo.Todo, name string) error {
+  i := s.indexOfList(name)
+  if i == -1 {
+    return fmt.Errorf("no list named %q", name)
+  }
+  if i == s.active {
+    return nil
+  }
+  target := s.lists[i]
+
+  before := s.snapshot()
+  parent, found := s.findItemAndParent(item.Id, nil)
+  if found == nil {
+    return nil
+  }
+  moved := *found
+  moved.Record(time.Now(), repo.EventMoved, s.ActiveList(), name)
+
+  // the other list is saved first so a failure there doesn't lose the item
+  move := &listMove{list: target, item: moved}
+  if err := move.apply(); err != nil {
+    return err
+  }
+  list := s.siblings(parent)
+  from := indexOf(*list, item.Id)
+  *list = append((*list)[:from], (*list)[from+1:]...)
+  s.record("move '" + item.Name + "' to " + name, before)
+  s.undo[len(s.undo)-1].moved = move
+  return s.repo.Persist()
+}
+
+// apply puts the moved item into its list, or takes it out again if it's
+// there already, wherever it was moved to since. The list is left as it was
+// if it can't be saved.
+func (m *listMove) apply() error {
+  r := m.list.Repo
+  before := repo.Clone(r.Todos)
+  other := &Service{repo: r}
+  if parent, found := other.findItemAndParent(m.item.Id, nil); found != nil {
+    list := other.siblings(parent)
+    i := indexOf(*list, m.item.Id)
+    *list = append((*list)[:i:i], (*list)[i+1:]...)
+  } else {
+    r.Todos = append(r.Todos, repo.Clone([]repo.Todo{m.item})...)
+  }
+  if err := r.Persist(); err != nil {
+    r.Todos = before
+    return err
+  }
+  m.list.undo, m.list.redo = nil, nil
+  return nil
+}
@@ -72,3 +72,19 @@ var CheckBox = lipgloss.NewStyle().Background(lipgloss.Color("#ffcbcd")).Foregro
 var CheckBoxBracket = lipgloss.NewStyle().Foreground(lipgloss.Color("#deae81"))
 var ActionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00b1ff"))
 var ParentColor = lipgloss.NewStyle().Foreground(lipgloss.Color("#87a987"))
+var StatusError = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f"))
+var DiffAdded = lipgloss.NewStyle().Foreground(lipgloss.Color("#87a987"))
+var DiffRemoved = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f"))
+var Due = lipgloss.NewStyle().Foreground(lipgloss.Color("#8c8c8c"))
+var DueToday = lipgloss.NewStyle().Foreground(lipgloss.Color("#deae81")).Bold(true)
+var Overdue = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f")).Bold(true)
+var PriorityHigh = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f")).Bold(true)
+var PriorityMedium = lipgloss.NewStyle().Foreground(lipgloss.Color("#deae81")).Bold(true)
+var PriorityLow = lipgloss.NewStyle().Foreground(lipgloss.Color("#00b1ff"))
+var PriorityOther = lipgloss.NewStyle().Foreground(lipgloss.Color("#8c8c8c"))
+var Tag = lipgloss.NewStyle().Foreground(lipgloss.Color("#151837")).Background(lipgloss.Color("#87a987")).Padding(0, 1)
+var Mention = lipgloss.NewStyle().Foreground(lipgloss.Color("#151837")).Background(lipgloss.Color("#deae81")).Padding(0, 1)
+var DetailPane = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("#595959")).PaddingLeft(1)
+var NotesIndicator = lipgloss.NewStyle().Foreground(lipgloss.Color("#8c8c8c"))
+var SearchMatch = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffcbcd")).Bold(true).Underline(true)
+var GroupHeader = lipgloss.NewStyle().Foreground(lipgloss.Color("#87a987")).Bold(true).Underline(true)
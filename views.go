@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/jquag/tui-do/bubbles/tabs"
+	"github.com/jquag/tui-do/service"
+)
+
+// view is the state of a tab: the built-in TODO and Complete tabs, then
+// the saved views from the config file. Each keeps its own cursor row and
+// scroll offset.
+type view struct {
+  // saved is nil for the built-in tabs
+  saved *service.View
+  cursorRow int
+  // offset is where the list was scrolled to when the tab was last shown
+  offset int
+}
+
+// loadViews sets up the tabs, with one for each view in the config file.
+func (m Model) loadViews() Model {
+  names := []string{"TODO", "Complete"}
+  m.views = []view{{}, {}}
+
+  var config service.Config
+  if *configFile != "" {
+    var err error
+    config, err = service.LoadConfig(*configFile)
+    if err != nil {
+      m.err = err
+    }
+  }
+  for i := range config.Views {
+    names = append(names, config.Views[i].Name)
+    m.views = append(m.views, view{saved: &config.Views[i]})
+  }
+
+  m.Tabs = tabs.New(names...)
+  return m
+}